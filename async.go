@@ -0,0 +1,26 @@
+package fsm
+
+import (
+	"context"
+	"fmt"
+)
+
+// TriggerAsync fires a event like Trigger in a new goroutine and calls done with the state the object ends in and
+// the error, so long-running actions don't block the caller. The state machine stays stateless: update the state
+// of the object in done. Triggers of the same object must not overlap, the caller serializes them.
+// A panic of the delegate is recovered and passed to done as an error, the state is then currentState.
+func (m *StateMachine) TriggerAsync(currentState string, event string, done func(newState string, err error), args ...interface{}) {
+	go func() {
+		var state string
+		var err error
+		defer func() {
+			if r := recover(); r != nil {
+				state, err = currentState, fmt.Errorf("state machine error: panic when processing event [%s]: %v", event, r)
+			}
+			if done != nil {
+				done(state, err)
+			}
+		}()
+		state, err = m.trigger(currentState, event, args, m.observe(context.Background(), nil))
+	}()
+}
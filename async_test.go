@@ -0,0 +1,70 @@
+package fsm
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// blockingProcessor blocks the actions until their channel is closed, and panics for the "explode" action.
+type blockingProcessor struct {
+	recordingProcessor
+	release map[string]chan struct{}
+}
+
+func (p *blockingProcessor) Action(action string, fromState string, toState string, args []interface{}) error {
+	if action == "explode" {
+		panic("boom")
+	}
+	<-p.release[action]
+	return nil
+}
+
+func (p *blockingProcessor) ActionContext(c *EventContext) error {
+	return p.Action(c.Action, c.From, c.To, c.Args)
+}
+
+func (p *blockingProcessor) OnExit(fromState string, args []interface{}) {}
+
+func (p *blockingProcessor) OnEnter(toState string, args []interface{}) {}
+
+func TestTriggerAsync(t *testing.T) {
+	p := &blockingProcessor{release: map[string]chan struct{}{"slow": make(chan struct{}), "fast": make(chan struct{})}}
+	fsm := NewStateMachine(&DefaultDelegate{P: p},
+		Transition{From: "A", Event: "Slow", To: "B", Action: "slow"},
+		Transition{From: "A", Event: "Fast", To: "C", Action: "fast"},
+		Transition{From: "A", Event: "Explode", To: "D", Action: "explode"},
+	)
+
+	type result struct {
+		state string
+		err   error
+	}
+	results := make(chan result, 3)
+	done := func(state string, err error) {
+		results <- result{state, err}
+	}
+
+	fsm.TriggerAsync("A", "Slow", done)
+	fsm.TriggerAsync("A", "Fast", done)
+
+	select {
+	case r := <-results:
+		t.Fatalf("expected the triggers to block, got %v", r)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(p.release["fast"])
+	if r := <-results; r.state != "C" || r.err != nil {
+		t.Errorf("expected the fast trigger to complete first in C, got %v", r)
+	}
+	close(p.release["slow"])
+	if r := <-results; r.state != "B" || r.err != nil {
+		t.Errorf("expected the slow trigger to complete in B, got %v", r)
+	}
+
+	fsm.TriggerAsync("A", "Explode", done)
+	if r := <-results; r.state != "A" || r.err == nil || errors.Is(r.err, ErrNoTransition) {
+		t.Errorf("expected the panic to be recovered in A, got %v", r)
+	}
+}
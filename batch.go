@@ -0,0 +1,60 @@
+package fsm
+
+import (
+	"context"
+	"sync"
+)
+
+// TriggerItem is an event fired by TriggerBatch for one object.
+type TriggerItem struct {
+	CurrentState string
+	Event        string
+	Args         []interface{}
+}
+
+// TriggerBatch fires the events of items concurrently, with at most concurrency triggers running at the same time.
+// The returned errors are aligned with items by index. Items that have not started when ctx is done get ctx.Err().
+func (m *StateMachine) TriggerBatch(ctx context.Context, items []TriggerItem, concurrency int) []error {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	errs := make([]error, len(items))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i := range items {
+		if !acquire(ctx, sem) {
+			for j := i; j < len(items); j++ {
+				errs[j] = ctx.Err()
+			}
+			break
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			item := items[i]
+			errs[i] = m.Trigger(item.CurrentState, item.Event, item.Args...)
+		}(i)
+	}
+
+	wg.Wait()
+	return errs
+}
+
+// acquire takes a slot of sem, it returns false if ctx is done first.
+func acquire(ctx context.Context, sem chan struct{}) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	select {
+	case <-ctx.Done():
+		return false
+	case sem <- struct{}{}:
+		return true
+	}
+}
@@ -0,0 +1,92 @@
+package fsm
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+// counterProcessor counts actions and sets the state of turnstiles, it is safe for concurrent use.
+type counterProcessor struct {
+	actions int64
+	running int64
+	max     int64
+}
+
+func (p *counterProcessor) OnExit(fromState string, args []interface{}) {}
+
+func (p *counterProcessor) Action(action string, fromState string, toState string, args []interface{}) error {
+	n := atomic.AddInt64(&p.running, 1)
+	defer atomic.AddInt64(&p.running, -1)
+	for {
+		max := atomic.LoadInt64(&p.max)
+		if n <= max || atomic.CompareAndSwapInt64(&p.max, max, n) {
+			break
+		}
+	}
+
+	atomic.AddInt64(&p.actions, 1)
+	if action == "fail" {
+		return fmt.Errorf("action %s failed", action)
+	}
+	return nil
+}
+
+func (p *counterProcessor) OnActionFailure(action string, fromState string, toState string, args []interface{}, err error) {
+}
+
+func (p *counterProcessor) OnEnter(toState string, args []interface{}) {
+	args[0].(*Turnstile).State = toState
+}
+
+func TestTriggerBatch(t *testing.T) {
+	p := &counterProcessor{}
+	fsm := NewStateMachine(&DefaultDelegate{P: p},
+		Transition{From: "Locked", Event: "Coin", To: "Unlocked", Action: "check"},
+		Transition{From: "Unlocked", Event: "Coin", To: "Unlocked", Action: "fail"},
+	)
+
+	items := make([]TriggerItem, 1000)
+	turnstiles := make([]*Turnstile, len(items))
+	for i := range items {
+		state := "Locked"
+		if i%10 == 0 {
+			state = "Unlocked"
+		}
+		turnstiles[i] = &Turnstile{ID: uint64(i), State: state}
+		items[i] = TriggerItem{CurrentState: state, Event: "Coin", Args: []interface{}{turnstiles[i]}}
+	}
+
+	errs := fsm.TriggerBatch(context.Background(), items, 16)
+	if len(errs) != len(items) {
+		t.Fatalf("expected %d results, got %d", len(items), len(errs))
+	}
+	for i, err := range errs {
+		if (i%10 == 0) != (err != nil) {
+			t.Errorf("item %d: unexpected result %v", i, err)
+		}
+		if turnstiles[i].State != "Unlocked" {
+			t.Errorf("item %d: unexpected state %s", i, turnstiles[i].State)
+		}
+	}
+	if p.actions != 1000 {
+		t.Errorf("expected 1000 actions, got %d", p.actions)
+	}
+	if p.max > 16 {
+		t.Errorf("expected at most 16 concurrent triggers, got %d", p.max)
+	}
+}
+
+func TestTriggerBatchCanceled(t *testing.T) {
+	fsm := NewStateMachine(&DefaultDelegate{P: &counterProcessor{}},
+		Transition{From: "Locked", Event: "Coin", To: "Unlocked", Action: "check"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	errs := fsm.TriggerBatch(ctx, []TriggerItem{{CurrentState: "Locked", Event: "Coin"}}, 4)
+	if errs[0] != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", errs[0])
+	}
+}
@@ -0,0 +1,175 @@
+package fsm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// binaryVersion is the first byte of the binary encoding of transitions, it must be incremented whenever the layout
+// changes. Other versions are rejected.
+const binaryVersion = 1
+
+var errBadBinary = errors.New("state machine error: invalid binary definition")
+
+// MarshalBinary implements encoding.BinaryMarshaler, it encodes the transitions compactly: states, events, actions
+// and guard names are stored once in a string table and referenced by varint indexes.
+// Guard functions can't be encoded, only the guard names are kept. The delegate and options are not encoded,
+// except the version of WithVersion.
+func (m *StateMachine) MarshalBinary() ([]byte, error) {
+	var table []string
+	indexes := make(map[string]uint64)
+	ref := func(s string) uint64 {
+		i, ok := indexes[s]
+		if !ok {
+			i = uint64(len(table))
+			indexes[s] = i
+			table = append(table, s)
+		}
+		return i
+	}
+
+	var body []byte
+	body = appendUvarint(body, ref(m.version))
+	body = appendUvarint(body, uint64(len(m.transitions)))
+	for _, t := range m.transitions {
+		for _, s := range []string{t.From, t.Event, t.To, t.Action, t.GuardName, t.OnGuardFail, t.Condition, t.OnFailure} {
+			body = appendUvarint(body, ref(s))
+		}
+		var flags byte
+		if t.Deprecated {
+			flags = 1
+		}
+		body = append(body, flags)
+		body = appendUvarint(body, uint64(t.Timeout))
+		body = appendUvarint(body, uint64(len(t.Tags)))
+		for _, tag := range t.Tags {
+			body = appendUvarint(body, ref(tag))
+		}
+		body = appendUvarint(body, uint64(t.Weight))
+	}
+
+	data := []byte{binaryVersion}
+	data = appendUvarint(data, uint64(len(table)))
+	for _, s := range table {
+		data = appendUvarint(data, uint64(len(s)))
+		data = append(data, s...)
+	}
+	return append(data, body...), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, it replaces the transitions with the decoded ones.
+// Decoded transitions have no Guard function, set them again by GuardName if needed.
+// A zero StateMachine, e.g. allocated by gob, gets the defaults of NewStateMachine, but no delegate:
+// create the local machine with NewStateMachine(delegate, decoded.Transitions()...).
+func (m *StateMachine) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 || data[0] != binaryVersion {
+		return errBadBinary
+	}
+	data = data[1:]
+
+	next := func() (uint64, error) {
+		v, n := binary.Uvarint(data)
+		if n <= 0 {
+			return 0, errBadBinary
+		}
+		data = data[n:]
+		return v, nil
+	}
+
+	count, err := next()
+	if err != nil || count > uint64(len(data)) {
+		return errBadBinary
+	}
+	table := make([]string, count)
+	for i := range table {
+		size, err := next()
+		if err != nil || size > uint64(len(data)) {
+			return errBadBinary
+		}
+		table[i], data = string(data[:size]), data[size:]
+	}
+
+	ref, err := next()
+	if err != nil || ref >= uint64(len(table)) {
+		return errBadBinary
+	}
+	version := table[ref]
+
+	count, err = next()
+	if err != nil || count > uint64(len(data)) {
+		return errBadBinary
+	}
+	transitions := make([]Transition, count)
+	for i := range transitions {
+		var fields [8]string
+		for j := range fields {
+			ref, err := next()
+			if err != nil || ref >= uint64(len(table)) {
+				return errBadBinary
+			}
+			fields[j] = table[ref]
+		}
+		if len(data) == 0 || data[0] > 1 {
+			return errBadBinary
+		}
+		transitions[i] = Transition{From: fields[0], Event: fields[1], To: fields[2], Action: fields[3],
+			GuardName: fields[4], OnGuardFail: fields[5], Condition: fields[6], OnFailure: fields[7], Deprecated: data[0] == 1}
+		data = data[1:]
+
+		timeout, err := next()
+		if err != nil {
+			return errBadBinary
+		}
+		transitions[i].Timeout = time.Duration(timeout)
+
+		tags, err := next()
+		if err != nil || tags > uint64(len(data)) {
+			return errBadBinary
+		}
+		for j := uint64(0); j < tags; j++ {
+			ref, err := next()
+			if err != nil || ref >= uint64(len(table)) {
+				return errBadBinary
+			}
+			transitions[i].Tags = append(transitions[i].Tags, table[ref])
+		}
+
+		weight, err := next()
+		if err != nil {
+			return errBadBinary
+		}
+		transitions[i].Weight = int(weight)
+	}
+	if len(data) > 0 {
+		return fmt.Errorf("%w: %d trailing bytes", errBadBinary, len(data))
+	}
+
+	m.transitions = transitions
+	m.version = version
+	if m.matcher == nil {
+		m.matcher = LinearMatcher{}
+	}
+	if m.clock == nil {
+		m.clock = RealClock{}
+	}
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder with MarshalBinary, so the definition of a machine can be sent over net/rpc.
+func (m *StateMachine) GobEncode() ([]byte, error) {
+	return m.MarshalBinary()
+}
+
+// GobDecode implements gob.GobDecoder with UnmarshalBinary.
+func (m *StateMachine) GobDecode(data []byte) error {
+	return m.UnmarshalBinary(data)
+}
+
+// appendUvarint appends the varint encoding of v, like binary.AppendUvarint of Go 1.19.
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(b, buf[:n]...)
+}
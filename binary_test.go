@@ -0,0 +1,121 @@
+package fsm
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"net"
+	"net/rpc"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMarshalBinary(t *testing.T) {
+	fsm := NewStateMachine(nil,
+		Transition{From: "Locked", Event: "Coin", To: "Unlocked", Action: "check"},
+		Transition{From: "Locked", Event: "Push", To: "Locked", Action: "invalid-push", GuardName: "staffed", Condition: "staff > 0"},
+		Transition{From: "Unlocked", Event: "Push", To: "Locked", Action: "pass", OnFailure: "Broken", Deprecated: true},
+		Transition{From: "Unlocked", Event: "Timeout", To: "Locked", Action: "lock", Timeout: 30 * time.Second},
+		Transition{From: "Unlocked", Event: "Coin", To: "Unlocked", Action: "repeat-check", Tags: []string{"billing", "audit"}, Weight: 42},
+	)
+
+	data, err := fsm.MarshalBinary()
+	if err != nil {
+		t.Fatalf("marshal err: %v", err)
+	}
+	js, _ := json.Marshal(fsm.transitions)
+	if len(data) >= len(js) {
+		t.Errorf("expected the binary encoding to be smaller than JSON, got %d >= %d bytes", len(data), len(js))
+	}
+
+	decoded := NewStateMachine(nil)
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("unmarshal err: %v", err)
+	}
+	if !reflect.DeepEqual(decoded.transitions, fsm.transitions) {
+		t.Errorf("expected %v, got %v", fsm.transitions, decoded.transitions)
+	}
+
+	for i := 0; i < len(data); i++ {
+		if err := NewStateMachine(nil).UnmarshalBinary(data[:i]); err == nil {
+			t.Errorf("expected an error for %d truncated bytes", i)
+		}
+	}
+}
+
+func TestMarshalBinaryGob(t *testing.T) {
+	fsm := initFSM()
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(fsm); err != nil {
+		t.Fatalf("encode err: %v", err)
+	}
+	decoded := NewStateMachine(nil)
+	if err := gob.NewDecoder(&buf).Decode(decoded); err != nil {
+		t.Fatalf("decode err: %v", err)
+	}
+	if !reflect.DeepEqual(decoded.transitions, fsm.transitions) {
+		t.Errorf("expected %v, got %v", fsm.transitions, decoded.transitions)
+	}
+}
+
+// definitions serves machine definitions over net/rpc.
+type definitions struct {
+	machines map[string]*StateMachine
+}
+
+// Definition is the reply of definitions.Get.
+type Definition struct {
+	Machine *StateMachine
+}
+
+func (d *definitions) Get(name string, reply *Definition) error {
+	reply.Machine = d.machines[name]
+	return nil
+}
+
+func TestGobRPC(t *testing.T) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("Definitions", &definitions{machines: map[string]*StateMachine{"turnstile": initFSM()}}); err != nil {
+		t.Fatalf("register err: %v", err)
+	}
+	serverConn, clientConn := net.Pipe()
+	go server.ServeConn(serverConn)
+	client := rpc.NewClient(clientConn)
+	defer client.Close()
+
+	var reply Definition
+	if err := client.Call("Definitions.Get", "turnstile", &reply); err != nil {
+		t.Fatalf("call err: %v", err)
+	}
+	decoded := reply.Machine
+	if !reflect.DeepEqual(decoded.Transitions(), initFSM().transitions) {
+		t.Errorf("expected the turnstile transitions, got %v", decoded.Transitions())
+	}
+
+	p := &recordingProcessor{}
+	worker := NewStateMachine(&DefaultDelegate{P: p}, decoded.Transitions()...)
+	if err := worker.Trigger("Locked", "Coin"); err != nil {
+		t.Errorf("trigger err: %v", err)
+	}
+}
+
+func TestMarshalBinaryVersion(t *testing.T) {
+	data, err := initFSM().With(WithVersion("2.1")).MarshalBinary()
+	if err != nil {
+		t.Fatalf("marshal err: %v", err)
+	}
+	decoded := NewStateMachine(nil)
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("unmarshal err: %v", err)
+	}
+	if decoded.Version() != "2.1" || len(decoded.transitions) != 4 {
+		t.Errorf("expected version 2.1 and 4 transitions, got %q and %v", decoded.Version(), decoded.transitions)
+	}
+
+	data[0] = binaryVersion + 1
+	if err := NewStateMachine(nil).UnmarshalBinary(data); err != errBadBinary {
+		t.Errorf("expected an unknown format version to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,35 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+)
+
+// Message is a message of an event bus, its Type is the event.
+type Message struct {
+	Type    string
+	Payload interface{}
+}
+
+// EventSource is a minimal event bus adapter, see Subscribe.
+type EventSource interface {
+	// Messages returns the channel of messages, it is closed when the source stops.
+	Messages() <-chan Message
+}
+
+// Subscribe triggers the Type of each message of the bus as an event until the channel of the bus is closed.
+// stateOf returns the current state of the object the message is about, and args the args of its transition,
+// a nil args passes the message. Messages without a transition in the current state are skipped since a bus
+// carries messages for other consumers too. Subscribe stops at the first other error and returns it.
+func (m *StateMachine) Subscribe(bus EventSource, stateOf func(msg Message) string, args func(msg Message) []interface{}) error {
+	if args == nil {
+		args = func(msg Message) []interface{} { return []interface{}{msg} }
+	}
+	for msg := range bus.Messages() {
+		_, err := m.trigger(stateOf(msg), msg.Type, args(msg), m.observe(context.Background(), nil))
+		if err != nil && !errors.Is(err, ErrNoTransition) {
+			return err
+		}
+	}
+	return nil
+}
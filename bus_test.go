@@ -0,0 +1,56 @@
+package fsm
+
+import (
+	"errors"
+	"testing"
+)
+
+// memoryBus is an in-memory EventSource.
+type memoryBus chan Message
+
+func (b memoryBus) Messages() <-chan Message {
+	return b
+}
+
+func TestSubscribe(t *testing.T) {
+	fsm := initFSM()
+	ts := &Turnstile{State: "Locked"}
+
+	bus := make(memoryBus, 4)
+	bus <- Message{Type: "Coin", Payload: ts}
+	bus <- Message{Type: "Audit", Payload: ts}
+	bus <- Message{Type: "Push", Payload: ts}
+	close(bus)
+
+	err := fsm.Subscribe(bus, func(msg Message) string {
+		return msg.Payload.(*Turnstile).State
+	}, func(msg Message) []interface{} {
+		return []interface{}{msg.Payload}
+	})
+	if err != nil {
+		t.Fatalf("subscribe err: %v", err)
+	}
+	if ts.State != "Locked" || ts.CoinCount != 1 || ts.PassCount != 1 {
+		t.Errorf("expected a coin and a pass, got %+v", ts)
+	}
+}
+
+func TestSubscribeError(t *testing.T) {
+	fsm := NewStateMachine(&DefaultDelegate{P: &failingProcessor{}},
+		Transition{From: "Locked", Event: "Coin", To: "Unlocked", Action: "check"},
+	)
+
+	bus := make(memoryBus, 2)
+	bus <- Message{Type: "Coin"}
+	bus <- Message{Type: "Coin"}
+	close(bus)
+
+	err := fsm.Subscribe(bus, func(msg Message) string { return "Locked" }, nil)
+	var ae *actionError
+	if !errors.As(err, &ae) {
+		t.Fatalf("expected the action error, got %v", err)
+	}
+	if len(bus) != 1 {
+		t.Errorf("expected Subscribe to stop at the first error, %d messages left", len(bus))
+	}
+}
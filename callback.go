@@ -1,5 +1,12 @@
 package fsm
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
 // EventProcessor defines OnExit, Action and OnEnter actions.
 type EventProcessor interface {
 	// OnExit Action handles exiting a state
@@ -12,27 +19,262 @@ type EventProcessor interface {
 	OnEnter(toState string, args []interface{})
 }
 
+// ErrActionNotFound is returned, or wrapped, by processors that have no handler for an action, e.g. the default
+// branch of the switch in Action. DefaultDelegate then calls FallbackAction if the processor implements
+// FallbackProcessor.
+var ErrActionNotFound = errors.New("state machine error: action not found")
+
+// FallbackProcessor is an optional interface an EventProcessor can implement to handle the actions it has no handler
+// for generically, e.g. with reflection dispatch.
+type FallbackProcessor interface {
+	// FallbackAction handles an action whose handler returned ErrActionNotFound.
+	FallbackAction(action string, fromState string, toState string, args []interface{}) error
+}
+
+// EntryVetoer is an optional interface an EventProcessor can implement to veto entering a state, e.g. when the
+// state has reached its capacity. DefaultDelegate calls CanEnter after the action has succeeded and before the
+// entry action and OnEnter of the state.
+type EntryVetoer interface {
+	// CanEnter returns a non-nil error if the state can't be entered.
+	CanEnter(toState string, args []interface{}) error
+}
+
+// EntryVetoedError is returned by DefaultDelegate when CanEnter vetoes entering a state.
+// It unwraps to the error returned by CanEnter.
+type EntryVetoedError struct {
+	// State is the state that was not entered.
+	State string
+	// Err is the error returned by CanEnter.
+	Err error
+}
+
+func (e *EntryVetoedError) Error() string {
+	return fmt.Sprintf("state machine error: entry to state [%s] vetoed: %v", e.State, e.Err)
+}
+
+func (e *EntryVetoedError) Unwrap() error {
+	return e.Err
+}
+
+// ActionErrors is returned by DefaultDelegate with WithContinueOnActionError, it lists the errors of the actions
+// of a transition that failed, in the order the actions ran. errors.Is and errors.As match any of the errors.
+type ActionErrors []error
+
+func (e ActionErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the errors.
+func (e ActionErrors) Unwrap() []error {
+	return e
+}
+
+// Is reports whether any of the errors matches target.
+func (e ActionErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of the errors that matches target.
+func (e ActionErrors) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
 // DefaultDelegate is a default delegate.
-// it splits processing of actions into three actions: OnExit, Action and OnEnter.
+// it splits processing of actions into three actions: OnExit, Action and OnEnter, which always run in this order.
+// Use CompositeProcessor to run several processors with a specified order.
 type DefaultDelegate struct {
 	P EventProcessor
 }
 
 // HandleEvent implements Delegate interface and split HandleEvent into three actions.
 func (dd *DefaultDelegate) HandleEvent(action string, fromState string, toState string, args []interface{}) error {
-	if fromState != toState {
-		dd.P.OnExit(fromState, args)
+	return dd.HandleEventContext(&EventContext{Action: action, From: fromState, To: toState, Args: args})
+}
+
+// HandleEventContext implements ContextDelegate interface. The context is passed to the processor if it implements
+// ContextProcessor. OnEnter is called with the state the action redirected to, if any.
+// OnExit and OnEnter always come in pairs: OnExit runs before the action when the declared To differs from From.
+// If the action redirects a self-transition to another state, OnExit runs after the action, and if the action
+// redirects back to From after OnExit has run, From is entered again.
+// The exit action of From registered with StateExit runs after OnExit, and the entry action of To registered with
+// StateEntry runs before OnEnter, so the order is: exit action, transition action, entry action.
+// If the processor implements ArgsProcessor, the steps after an action receive the args it returned.
+// If the processor implements EntryVetoer and CanEnter fails, the state is not entered and OnActionFailure is called
+// with an *EntryVetoedError.
+// If the action fails and the transition has an OnFailure state, it is entered after OnActionFailure.
+// The first failing action stops the transition. With WithContinueOnActionError, the exit action, the transition
+// action and the entry action all run, OnActionFailure is called for each failure, the state is not entered if any
+// of them failed and the error is ActionErrors. If the transition has an OnFailure state, it is entered after all
+// actions ran.
+func (dd *DefaultDelegate) HandleEventContext(c *EventContext) error {
+	var errs ActionErrors
+	// failed records the error of an action, it reports whether the transition stops.
+	failed := func(err error) bool {
+		if err == nil {
+			return false
+		}
+		errs = append(errs, err)
+		return !c.continueOnActionError
+	}
+	// result returns the error of the transition after all actions ran with WithContinueOnActionError.
+	result := func() error {
+		if len(errs) > 0 && c.OnFailure != "" {
+			return dd.compensate(c, errs.err(c))
+		}
+		return errs.err(c)
+	}
+
+	toState := c.To
+	exited := c.ChangesState()
+	if exited {
+		dd.P.OnExit(c.From, c.Args)
+		c.exited = true
+		if failed(dd.stateAction(c, c.exitActions[c.From], toState)) {
+			return errs.err(c)
+		}
+	}
+
+	if c.Action != "" {
+		if failed(dd.action(c, toState)) {
+			if c.OnFailure != "" {
+				return dd.compensate(c, errs.err(c))
+			}
+			return errs.err(c)
+		}
 	}
 
-	err := dd.P.Action(action, fromState, toState, args)
+	if c.To == "" {
+		c.To = toState
+	}
+	if !exited && c.ChangesState() {
+		dd.P.OnExit(c.From, c.Args)
+		exited = true
+		c.exited = true
+		if failed(dd.stateAction(c, c.exitActions[c.From], toState)) {
+			return errs.err(c)
+		}
+	}
+	if exited {
+		if err := canEnter(dd.P, c.To, c.Args); err != nil {
+			err = &EntryVetoedError{State: c.To, Err: err}
+			dd.P.OnActionFailure(c.Action, c.From, c.To, c.Args, err)
+			errs = append(errs, err)
+			return errs.err(c)
+		}
+		if failed(dd.stateAction(c, c.entryActions[c.To], toState)) || len(errs) > 0 {
+			return result()
+		}
+		dd.P.OnEnter(c.To, c.Args)
+		c.entered = true
+	}
+
+	return result()
+}
+
+// compensate enters the OnFailure state after the action failed with err. OnExit of From is called if it has not
+// been called yet, and OnEnter is called for the OnFailure state unless the object stays in From.
+func (dd *DefaultDelegate) compensate(c *EventContext, err error) error {
+	if !c.exited && c.OnFailure != c.From {
+		dd.P.OnExit(c.From, c.Args)
+		c.exited = true
+	}
+	c.To = c.OnFailure
+	if c.exited {
+		dd.P.OnEnter(c.To, c.Args)
+		c.entered = true
+	}
+	return &CompensationError{Action: c.Action, State: c.OnFailure, Err: err}
+}
+
+// err returns the error of a transition: nil without errors, the error of the failed action by default, or all
+// errors with WithContinueOnActionError.
+func (e ActionErrors) err(c *EventContext) error {
+	if len(e) == 0 {
+		return nil
+	}
+	if !c.continueOnActionError {
+		return e[0]
+	}
+	return e
+}
+
+// action runs the action of the context with the processor, a failure is reported with the declared toState.
+func (dd *DefaultDelegate) action(c *EventContext, toState string) error {
+	var start time.Time
+	if c.actionTimer != nil {
+		start = c.clock.Now()
+	}
+	err := runAction(dd.P, c)
+	if c.actionTimer != nil {
+		c.actionTimer(c.Action, c.clock.Now().Sub(start))
+	}
 	if err != nil {
-		dd.P.OnActionFailure(action, fromState, toState, args, err)
-		return err
+		dd.P.OnActionFailure(c.Action, c.From, toState, c.Args, err)
 	}
+	return err
+}
 
-	if fromState != toState {
-		dd.P.OnEnter(toState, args)
+// runAction runs the action of the context with the processor through ContextProcessor or ArgsProcessor if the
+// processor implements them, or through Action. If the action is not found, it runs FallbackAction of a
+// FallbackProcessor.
+func runAction(p EventProcessor, c *EventContext) error {
+	err := runPrimaryAction(p, c)
+	if fp, ok := p.(FallbackProcessor); ok && errors.Is(err, ErrActionNotFound) {
+		return fp.FallbackAction(c.Action, c.From, c.To, c.Args)
 	}
+	return err
+}
 
+// runPrimaryAction runs the action of the context with the handler of the processor.
+func runPrimaryAction(p EventProcessor, c *EventContext) error {
+	if cp, ok := p.(ContextProcessor); ok {
+		return cp.ActionContext(c)
+	}
+	if ap, ok := p.(ArgsProcessor); ok {
+		args, err := ap.ActionArgs(c.Action, c.From, c.To, c.Args)
+		if err == nil {
+			c.Args = args
+		}
+		return err
+	}
+	return p.Action(c.Action, c.From, c.To, c.Args)
+}
+
+// canEnter calls CanEnter if the processor implements EntryVetoer.
+func canEnter(p EventProcessor, toState string, args []interface{}) error {
+	if v, ok := p.(EntryVetoer); ok {
+		return v.CanEnter(toState, args)
+	}
 	return nil
 }
+
+// stateAction runs an entry or exit action like a transition action, it can't redirect the transition.
+func (dd *DefaultDelegate) stateAction(c *EventContext, action string, toState string) error {
+	if action == "" {
+		return nil
+	}
+	sc := *c
+	sc.Action = action
+	err := dd.action(&sc, toState)
+	c.Args = sc.Args
+	return err
+}
+
+// OnReset implements ArgsResetter interface, it forwards to the processor if it implements Resetter or ArgsResetter.
+func (dd *DefaultDelegate) OnReset(args []interface{}) {
+	reset(dd.P, args)
+}
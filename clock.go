@@ -0,0 +1,25 @@
+package fsm
+
+import "time"
+
+// Clock provides the current time and timers. All duration-based behavior of the state machine goes through a Clock,
+// so tests can inject a fake clock instead of sleeping.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// After waits for the duration to elapse and then sends the current time on the returned channel.
+	After(d time.Duration) <-chan time.Time
+}
+
+// RealClock is the default Clock backed by the time package.
+type RealClock struct{}
+
+// Now implements Clock interface.
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+// After implements Clock interface.
+func (RealClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
@@ -0,0 +1,87 @@
+package fsm
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a manually advanced Clock for tests.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+type fakeWaiter struct {
+	at time.Time
+	c  chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2019, 3, 6, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, fakeWaiter{at: c.now.Add(d), c: ch})
+	return ch
+}
+
+// Advance moves the clock forward and fires the expired timers.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	waiters := c.waiters[:0]
+	for _, w := range c.waiters {
+		if !w.at.After(c.now) {
+			w.c <- c.now
+		} else {
+			waiters = append(waiters, w)
+		}
+	}
+	c.waiters = waiters
+}
+
+func TestWithClock(t *testing.T) {
+	fsm := initFSM()
+	if _, ok := fsm.clock.(RealClock); !ok {
+		t.Errorf("expected RealClock by default, got %T", fsm.clock)
+	}
+
+	clock := newFakeClock()
+	fsm.With(WithClock(clock))
+	if fsm.clock != clock {
+		t.Errorf("expected the injected clock, got %T", fsm.clock)
+	}
+
+	after := clock.After(time.Second)
+	clock.Advance(500 * time.Millisecond)
+	select {
+	case <-after:
+		t.Fatal("timer fired too early")
+	default:
+	}
+	clock.Advance(500 * time.Millisecond)
+	select {
+	case now := <-after:
+		if !now.Equal(clock.Now()) {
+			t.Errorf("expected %v, got %v", clock.Now(), now)
+		}
+	default:
+		t.Fatal("timer did not fire")
+	}
+}
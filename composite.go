@@ -0,0 +1,65 @@
+package fsm
+
+// CompositeProcessor aggregates multiple EventProcessors. Used by DefaultDelegate, a transition runs OnExit of all
+// processors, then Action of all processors, then OnEnter of all processors, each step in the order of the processors.
+// Action stops at the first error, and OnActionFailure is called on all processors.
+type CompositeProcessor []EventProcessor
+
+// OnExit implements EventProcessor interface.
+func (c CompositeProcessor) OnExit(fromState string, args []interface{}) {
+	for _, p := range c {
+		p.OnExit(fromState, args)
+	}
+}
+
+// Action implements EventProcessor interface.
+func (c CompositeProcessor) Action(action string, fromState string, toState string, args []interface{}) error {
+	for _, p := range c {
+		if err := p.Action(action, fromState, toState, args); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ActionContext implements ContextProcessor interface, processors that don't implement ContextProcessor run
+// their ActionArgs or Action. Args updated by a processor are passed to the following processors.
+func (c CompositeProcessor) ActionContext(ec *EventContext) error {
+	for _, p := range c {
+		if err := runAction(p, ec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OnActionFailure implements EventProcessor interface.
+func (c CompositeProcessor) OnActionFailure(action string, fromState string, toState string, args []interface{}, err error) {
+	for _, p := range c {
+		p.OnActionFailure(action, fromState, toState, args, err)
+	}
+}
+
+// CanEnter implements EntryVetoer interface, it returns the first error of the processors that implement EntryVetoer.
+func (c CompositeProcessor) CanEnter(toState string, args []interface{}) error {
+	for _, p := range c {
+		if err := canEnter(p, toState, args); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OnEnter implements EventProcessor interface.
+func (c CompositeProcessor) OnEnter(toState string, args []interface{}) {
+	for _, p := range c {
+		p.OnEnter(toState, args)
+	}
+}
+
+// OnReset implements ArgsResetter interface and forwards to the processors in order.
+func (c CompositeProcessor) OnReset(args []interface{}) {
+	for _, p := range c {
+		reset(p, args)
+	}
+}
@@ -0,0 +1,70 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// orderProcessor appends its callbacks to a shared log.
+type orderProcessor struct {
+	name string
+	log  *[]string
+	fail bool
+}
+
+func (p *orderProcessor) OnExit(fromState string, args []interface{}) {
+	*p.log = append(*p.log, p.name+".exit")
+}
+
+func (p *orderProcessor) Action(action string, fromState string, toState string, args []interface{}) error {
+	*p.log = append(*p.log, p.name+".action")
+	if p.fail {
+		return errors.New("failed")
+	}
+	return nil
+}
+
+func (p *orderProcessor) OnActionFailure(action string, fromState string, toState string, args []interface{}, err error) {
+	*p.log = append(*p.log, p.name+".failure")
+}
+
+func (p *orderProcessor) OnEnter(toState string, args []interface{}) {
+	*p.log = append(*p.log, p.name+".enter")
+}
+
+func TestCompositeProcessorOrder(t *testing.T) {
+	var log []string
+	p := CompositeProcessor{
+		&orderProcessor{name: "persistence", log: &log},
+		&orderProcessor{name: "logging", log: &log},
+	}
+	fsm := NewStateMachine(&DefaultDelegate{P: p}, Transition{From: "Locked", Event: "Coin", To: "Unlocked", Action: "check"})
+
+	if err := fsm.Trigger("Locked", "Coin"); err != nil {
+		t.Fatalf("trigger err: %v", err)
+	}
+
+	want := "[persistence.exit logging.exit persistence.action logging.action persistence.enter logging.enter]"
+	if fmt.Sprint(log) != want {
+		t.Errorf("expected %s, got %v", want, log)
+	}
+}
+
+func TestCompositeProcessorFailure(t *testing.T) {
+	var log []string
+	p := CompositeProcessor{
+		&orderProcessor{name: "first", log: &log, fail: true},
+		&orderProcessor{name: "second", log: &log},
+	}
+	fsm := NewStateMachine(&DefaultDelegate{P: p}, Transition{From: "Locked", Event: "Coin", To: "Unlocked", Action: "check"})
+
+	if err := fsm.Trigger("Locked", "Coin"); err == nil {
+		t.Fatal("expected an action error")
+	}
+
+	want := "[first.exit second.exit first.action first.failure second.failure]"
+	if fmt.Sprint(log) != want {
+		t.Errorf("expected %s, got %v", want, log)
+	}
+}
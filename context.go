@@ -0,0 +1,78 @@
+package fsm
+
+import "time"
+
+// EventContext describes a transition being processed. It is passed to a ContextDelegate and, by DefaultDelegate,
+// to a ContextProcessor.
+type EventContext struct {
+	// Event is the triggered event, it is the real event even if the transition matched a wildcard Event.
+	Event string
+	// Action is the action of the transition.
+	Action string
+	// From is the current state of the processing object.
+	From string
+	// To is the state the object enters. An action may change it to redirect the transition to another state,
+	// OnEnter and follow-up events then use the new state. An empty To means no redirect.
+	// See DefaultDelegate.HandleEventContext for how OnExit and OnEnter are paired on redirects.
+	To string
+	// Args are the args passed to Trigger. An action may replace them, OnEnter and follow-up events then see the new args.
+	Args []interface{}
+	// OnFailure is the compensation state of the transition, see Transition.OnFailure. DefaultDelegate enters it when
+	// the action fails and returns a *CompensationError, a ContextDelegate that handles it must do the same.
+	OnFailure string
+	// Params are the parameters registered for the action with RegisterActionParams, nil if there are none.
+	// It is a copy for this transition, changing it doesn't change the registered params.
+	Params map[string]interface{}
+
+	emit func(event string)
+	// exited and entered report whether DefaultDelegate called OnExit and OnEnter, see TriggerRecorded.
+	exited, entered bool
+	// entryActions and exitActions are the state actions registered with StateEntry and StateExit.
+	entryActions, exitActions map[string]string
+	// output is the output of a MealyProcessor, see TriggerMealy.
+	output interface{}
+	// clock and actionTimer measure the action for WithActionTimer.
+	clock       Clock
+	actionTimer func(action string, d time.Duration)
+	// continueOnActionError runs the remaining actions after a failure, see WithContinueOnActionError.
+	continueOnActionError bool
+}
+
+// Emit enqueues a follow-up event. Follow-up events are processed by Trigger after the current transition has
+// completed (run-to-completion), starting from the state the transition entered. They are processed depth-first:
+// events emitted by a follow-up transition run before the remaining events emitted earlier.
+// Emit is a no-op if the context is not created by the state machine.
+func (c *EventContext) Emit(event string) {
+	if c.emit != nil {
+		c.emit(event)
+	}
+}
+
+// ChangesState reports whether the object leaves its current state for To, DefaultDelegate only calls OnExit and
+// OnEnter in that case. Unlike Transition.ChangesState, it compares the state the event was triggered in, so
+// a transition from Wildcard back to the triggered state doesn't change state.
+func (c *EventContext) ChangesState() bool {
+	return changesState(c.From, c.To)
+}
+
+// ArgsProcessor is an optional interface an EventProcessor can implement when its actions produce data for the
+// following steps. DefaultDelegate calls ActionArgs instead of Action, and OnEnter and follow-up events receive
+// the returned args. A ContextProcessor can replace EventContext.Args instead.
+type ArgsProcessor interface {
+	// ActionArgs is used to handle transitions, it returns the updated args.
+	ActionArgs(action string, fromState string, toState string, args []interface{}) ([]interface{}, error)
+}
+
+// ContextDelegate is an optional interface a Delegate can implement to receive the EventContext of transitions
+// instead of plain values. The state machine calls HandleEventContext instead of HandleEvent.
+type ContextDelegate interface {
+	// HandleEventContext handles transitions.
+	HandleEventContext(c *EventContext) error
+}
+
+// ContextProcessor is an optional interface an EventProcessor can implement when its actions need the EventContext,
+// e.g. to emit follow-up events or to redirect the transition. DefaultDelegate calls ActionContext instead of Action.
+type ContextProcessor interface {
+	// ActionContext is used to handle transitions.
+	ActionContext(c *EventContext) error
+}
@@ -0,0 +1,105 @@
+package fsm
+
+import (
+	"sort"
+	"strings"
+)
+
+// DiffReport lists the differences of the transitions of two state machines, sorted by From, Event and GuardName.
+type DiffReport struct {
+	Added   []Transition
+	Removed []Transition
+	Changed []TransitionChange
+}
+
+// TransitionChange is a transition whose To or Action changed.
+type TransitionChange struct {
+	Old Transition
+	New Transition
+}
+
+// Empty reports whether the machines have the same transitions.
+func (r DiffReport) Empty() bool {
+	return len(r.Added) == 0 && len(r.Removed) == 0 && len(r.Changed) == 0
+}
+
+// String returns a line per difference: "+ " for added, "- " for removed and "~ " for changed transitions.
+func (r DiffReport) String() string {
+	var b strings.Builder
+	for _, t := range r.Added {
+		b.WriteString("+ " + t.String() + "\n")
+	}
+	for _, t := range r.Removed {
+		b.WriteString("- " + t.String() + "\n")
+	}
+	for _, c := range r.Changed {
+		b.WriteString("~ " + c.Old.String() + " => " + c.New.String() + "\n")
+	}
+	return b.String()
+}
+
+// Diff compares the transitions of two versions of a state machine, e.g. for a review of protocol changes.
+// Transitions are matched by From, Event and GuardName, a matched transition is changed when its To or Action differs.
+func Diff(old, new *StateMachine) DiffReport {
+	oldByKey := transitionsByKey(old.transitions)
+	newByKey := transitionsByKey(new.transitions)
+
+	var r DiffReport
+	for key, n := range newByKey {
+		o, ok := oldByKey[key]
+		switch {
+		case !ok:
+			r.Added = append(r.Added, n)
+		case o.To != n.To || o.Action != n.Action:
+			r.Changed = append(r.Changed, TransitionChange{Old: o, New: n})
+		}
+	}
+	for key, o := range oldByKey {
+		if _, ok := newByKey[key]; !ok {
+			r.Removed = append(r.Removed, o)
+		}
+	}
+
+	sortTransitions(r.Added)
+	sortTransitions(r.Removed)
+	sort.Slice(r.Changed, func(i, j int) bool { return transitionLess(r.Changed[i].New, r.Changed[j].New) })
+	return r
+}
+
+// Version returns the version of the transition table set with WithVersion.
+func (m *StateMachine) Version() string {
+	return m.version
+}
+
+// CompatibleWith reports whether other can replace the machine, e.g. in a rolling upgrade: no transition is removed
+// or changed, see Diff, so objects processed by the machine keep working with other. Added transitions are compatible.
+func (m *StateMachine) CompatibleWith(other *StateMachine) bool {
+	r := Diff(m, other)
+	return len(r.Removed) == 0 && len(r.Changed) == 0
+}
+
+// transitionsByKey indexes the transitions by From, Event and GuardName, the first one wins.
+func transitionsByKey(transitions []Transition) map[[3]string]Transition {
+	byKey := make(map[[3]string]Transition, len(transitions))
+	for _, t := range transitions {
+		key := [3]string{t.From, t.Event, t.GuardName}
+		if _, ok := byKey[key]; !ok {
+			byKey[key] = t
+		}
+	}
+	return byKey
+}
+
+func sortTransitions(transitions []Transition) {
+	sort.Slice(transitions, func(i, j int) bool { return transitionLess(transitions[i], transitions[j]) })
+}
+
+func transitionLess(a, b Transition) bool {
+	if a.From != b.From {
+		return a.From < b.From
+	}
+	if a.Event != b.Event {
+		return a.Event < b.Event
+	}
+	return a.GuardName < b.GuardName
+}
@@ -0,0 +1,48 @@
+package fsm
+
+import "testing"
+
+func TestDiff(t *testing.T) {
+	v1 := NewStateMachine(nil,
+		Transition{From: "Locked", Event: "Coin", To: "Unlocked", Action: "check"},
+		Transition{From: "Locked", Event: "Push", To: "Locked", Action: "invalid-push"},
+		Transition{From: "Unlocked", Event: "Push", To: "Locked", Action: "pass"},
+	)
+	v2 := NewStateMachine(nil,
+		Transition{From: "Locked", Event: "Coin", To: "Unlocked", Action: "check-coin"},
+		Transition{From: "Unlocked", Event: "Push", To: "Locked", Action: "pass"},
+		Transition{From: "Unlocked", Event: "Coin", To: "Unlocked", Action: "refund"},
+		Transition{From: "Broken", Event: "Fix", To: "Locked", Action: "repair"},
+	)
+
+	r := Diff(v1, v2)
+	want := "+ Broken --(Fix/repair)--> Locked\n" +
+		"+ Unlocked --(Coin/refund)--> Unlocked\n" +
+		"- Locked --(Push/invalid-push)--> Locked\n" +
+		"~ Locked --(Coin/check)--> Unlocked => Locked --(Coin/check-coin)--> Unlocked\n"
+	if got := r.String(); got != want {
+		t.Errorf("expected report\n%s\ngot\n%s", want, got)
+	}
+
+	if r := Diff(v1, v1); !r.Empty() {
+		t.Errorf("expected no differences, got %s", r)
+	}
+}
+
+func TestCompatibleWith(t *testing.T) {
+	old := initFSM()
+
+	added := NewStateMachine(nil, append(old.Transitions(), Transition{From: "Locked", Event: "Kick", To: "Broken"})...)
+	if !old.CompatibleWith(added) {
+		t.Error("expected an added transition to be compatible")
+	}
+	if added.CompatibleWith(old) {
+		t.Error("expected a removed transition to be incompatible")
+	}
+
+	changed := old.Transitions()
+	changed[0].To = "Broken"
+	if old.CompatibleWith(NewStateMachine(nil, changed...)) {
+		t.Error("expected a changed transition to be incompatible")
+	}
+}
@@ -0,0 +1,382 @@
+package fsm
+
+import (
+	"fmt"
+	"io"
+	"math/bits"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Graphviz layout engines for ExportWithDetails.
+const (
+	LayoutDot   = "dot"
+	LayoutNeato = "neato"
+	LayoutCirco = "circo"
+	LayoutFdp   = "fdp"
+	LayoutSfdp  = "sfdp"
+	LayoutTwopi = "twopi"
+)
+
+// Export exports the state diagram into a file.
+func (m *StateMachine) Export(outfile string) error {
+	return m.ExportWithDetails(outfile, "png", LayoutDot, "72", "-Gsize=10,5 -Gdpi=200")
+}
+
+// ExportWithDetails  exports the state diagram with more graphviz options.
+// layout is one of the Layout constants, it returns an error for other engines.
+func (m *StateMachine) ExportWithDetails(outfile string, format string, layout string, scale string, more string) error {
+	switch layout {
+	case LayoutDot, LayoutNeato, LayoutCirco, LayoutFdp, LayoutSfdp, LayoutTwopi:
+	default:
+		return fmt.Errorf("state machine error: unknown graphviz layout %q", layout)
+	}
+
+	var dot strings.Builder
+	if err := m.WriteDot(&dot); err != nil {
+		return err
+	}
+
+	cmd := fmt.Sprintf("dot -o%s -T%s -K%s -s%s %s", outfile, format, layout, scale, more)
+
+	return system(cmd, dot.String())
+}
+
+// WriteDot writes the state diagram in graphviz DOT language.
+// A state machine without transitions is written as a valid empty digraph.
+func (m *StateMachine) WriteDot(w io.Writer) error {
+	return m.writeDot(w, m.transitions, "")
+}
+
+// ExportHighlight writes the DOT diagram with the current state of an object highlighted by a bold red border.
+func (m *StateMachine) ExportHighlight(w io.Writer, currentState string) error {
+	return m.writeDot(w, m.transitions, currentState)
+}
+
+// ExportNeighborhood writes the DOT diagram of the states within depth hops of the state, following transitions
+// in both directions, and the transitions between them. depth=1 means the state plus its direct neighbors.
+func (m *StateMachine) ExportNeighborhood(w io.Writer, state string, depth int) error {
+	near := map[string]bool{state: true}
+	frontier := []string{state}
+	for i := 0; i < depth && len(frontier) > 0; i++ {
+		var next []string
+		for _, s := range frontier {
+			for _, t := range m.transitions {
+				var neighbor string
+				switch s {
+				case t.From:
+					neighbor = t.To
+				case t.To:
+					neighbor = t.From
+				default:
+					continue
+				}
+				if !near[neighbor] {
+					near[neighbor] = true
+					next = append(next, neighbor)
+				}
+			}
+		}
+		frontier = next
+	}
+
+	var transitions []Transition
+	for _, t := range m.transitions {
+		if near[t.From] && near[t.To] {
+			transitions = append(transitions, t)
+		}
+	}
+	return m.writeDot(w, transitions, "")
+}
+
+// ExportFiltered writes the DOT diagram of the transitions for which keep returns true, e.g. to render only the happy
+// path without the guarded transitions.
+func (m *StateMachine) ExportFiltered(w io.Writer, keep func(Transition) bool) error {
+	var transitions []Transition
+	for _, t := range m.transitions {
+		if keep(t) {
+			transitions = append(transitions, t)
+		}
+	}
+	return m.writeDot(w, transitions, "")
+}
+
+// writeDot writes the diagram of the transitions, the highlight state is styled distinctly if it is not empty.
+func (m *StateMachine) writeDot(w io.Writer, transitions []Transition, highlight string) error {
+	dot := `digraph StateMachine {
+
+	rankdir=LR
+	node[width=1 fixedsize=true shape=circle style=filled fillcolor="darkorchid1" ]
+	
+	`
+
+	children := m.stateChildren()
+	if len(children) > 0 {
+		dot = dot + "compound=true\r\n"
+	}
+	dot = dot + m.dotGroups(transitions)
+	dot = dot + dotHierarchy(transitions, children)
+	dot = dot + m.dotStateActions(transitions)
+	if highlight != "" {
+		dot = dot + "\r\n" + fmt.Sprintf(`%s [color=red penwidth=3 fontcolor=red]`, dotID(highlight))
+	}
+	for _, t := range transitions {
+		dot = dot + "\r\n" + m.dotEdge(t, children)
+	}
+
+	dot = dot + m.dotLegend()
+	dot = dot + "\r\n}"
+
+	_, err := io.WriteString(w, dot)
+	return err
+}
+
+// dotGroups renders the configured state groups as clusters enclosing the states of the transitions.
+func (m *StateMachine) dotGroups(transitions []Transition) string {
+	if len(m.stateGroups) == 0 {
+		return ""
+	}
+
+	states := make(map[string]bool)
+	for _, t := range transitions {
+		states[t.From] = true
+		states[t.To] = true
+	}
+
+	names := make([]string, 0, len(m.stateGroups))
+	for name := range m.stateGroups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var dot string
+	for _, name := range names {
+		var nodes string
+		for _, state := range m.stateGroups[name] {
+			if states[state] {
+				nodes = nodes + "\t" + dotID(state) + "\r\n"
+			}
+		}
+		if nodes == "" {
+			continue
+		}
+		dot = dot + "\r\n" + fmt.Sprintf("subgraph \"cluster_%s\" {\r\n\tlabel=\"%s\"\r\n%s}", dotEscape(name), dotEscape(name), nodes)
+	}
+	return dot
+}
+
+// stateChildren returns the sorted child states of each composite state configured by WithStateParents.
+func (m *StateMachine) stateChildren() map[string][]string {
+	if len(m.stateParents) == 0 {
+		return nil
+	}
+	children := make(map[string][]string)
+	for child, parent := range m.stateParents {
+		children[parent] = append(children[parent], child)
+	}
+	for _, c := range children {
+		sort.Strings(c)
+	}
+	return children
+}
+
+// dotHierarchy renders the composite states as nested clusters. Clusters without a state of the transitions are omitted.
+func dotHierarchy(transitions []Transition, children map[string][]string) string {
+	if len(children) == 0 {
+		return ""
+	}
+
+	states := make(map[string]bool)
+	for _, t := range transitions {
+		states[t.From] = true
+		states[t.To] = true
+	}
+
+	var roots []string
+	for parent := range children {
+		if !hasParent(parent, children) {
+			roots = append(roots, parent)
+		}
+	}
+	sort.Strings(roots)
+
+	var dot string
+	for _, root := range roots {
+		if cluster := dotCluster(root, children, states, map[string]bool{}, "\t"); cluster != "" {
+			dot = dot + "\r\n" + cluster
+		}
+	}
+	return dot
+}
+
+// hasParent reports whether the state is a child of a composite state.
+func hasParent(state string, children map[string][]string) bool {
+	for _, c := range children {
+		for _, child := range c {
+			if child == state {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// dotCluster renders the composite state as a cluster with its children, visited stops cyclic parents.
+func dotCluster(parent string, children map[string][]string, states map[string]bool, visited map[string]bool, indent string) string {
+	visited[parent] = true
+	used := states[parent]
+	var nodes string
+	for _, child := range children[parent] {
+		if _, composite := children[child]; composite {
+			if visited[child] {
+				continue
+			}
+			if cluster := dotCluster(child, children, states, visited, indent+"\t"); cluster != "" {
+				nodes = nodes + indent + cluster + "\r\n"
+				used = true
+			}
+			continue
+		}
+		if states[child] {
+			used = true
+		}
+		nodes = nodes + indent + dotID(child) + "\r\n"
+	}
+	if !used {
+		return ""
+	}
+	return fmt.Sprintf("subgraph %s {\r\n%slabel=\"%s\"\r\n%s%s}", dotID("cluster_"+parent), indent, dotEscape(parent), nodes, indent[1:])
+}
+
+// dotStateActions renders the states with entry or exit actions, see StateEntry and StateExit, as record nodes
+// with a compartment for the name and one for the actions, like UML state boxes.
+func (m *StateMachine) dotStateActions(transitions []Transition) string {
+	if len(m.entryActions) == 0 && len(m.exitActions) == 0 {
+		return ""
+	}
+
+	var dot string
+	seen := map[string]bool{Wildcard: true}
+	for _, t := range transitions {
+		for _, state := range []string{t.From, t.To} {
+			if seen[state] {
+				continue
+			}
+			seen[state] = true
+			entry, exit := m.entryActions[state], m.exitActions[state]
+			if entry == "" && exit == "" {
+				continue
+			}
+			var actions string
+			if entry != "" {
+				actions = actions + `entry / ` + dotRecordEscape(entry) + `\l`
+			}
+			if exit != "" {
+				actions = actions + `exit / ` + dotRecordEscape(exit) + `\l`
+			}
+			dot = dot + "\r\n" + fmt.Sprintf(`%s [shape=record fixedsize=false label="{%s|%s}"]`, dotID(state), dotRecordEscape(state), actions)
+		}
+	}
+	return dot
+}
+
+// dotRecordEscape escapes a field of a record label, the characters {}|<> delimit fields.
+func dotRecordEscape(s string) string {
+	return strings.NewReplacer(`{`, `\{`, `}`, `\}`, `|`, `\|`, `<`, `\<`, `>`, `\>`).Replace(dotEscape(s))
+}
+
+// entryState returns the node an edge to the state connects to. A composite state is drawn as a cluster, so the edge
+// connects to its first child in name order and is clipped at the border of the cluster.
+func entryState(state string, children map[string][]string) (string, bool) {
+	node, composite := state, false
+	visited := make(map[string]bool)
+	for len(children[node]) > 0 && !visited[node] {
+		visited[node] = true
+		node, composite = children[node][0], true
+	}
+	return node, composite
+}
+
+// dotEdge renders a transition as an edge. Guarded transitions are dashed, so branching is visible at a glance,
+// and deprecated transitions are dashed and grey. The weight of a transition sets the DOT weight, and the pen width
+// grows with its logarithm, so hit counts stay readable.
+// Edges from or to a composite state connect to the border of its cluster.
+func (m *StateMachine) dotEdge(t Transition, children map[string][]string) string {
+	separator := " | "
+	if m.multilineLabels {
+		separator = `\n`
+	}
+	attrs := fmt.Sprintf(`label="%s%s%s"`, dotEscape(t.Event), separator, dotEscape(t.Action))
+	switch {
+	case t.Deprecated:
+		attrs = attrs + ` style=dashed color=grey`
+	case t.guarded() || t.GuardName != "" || t.Condition != "":
+		attrs = attrs + ` style=dashed`
+	}
+	if t.Weight > 0 {
+		attrs = attrs + fmt.Sprintf(" weight=%d penwidth=%d", t.Weight, bits.Len(uint(t.Weight)))
+	}
+
+	from, fromComposite := entryState(t.From, children)
+	if fromComposite {
+		attrs = attrs + " ltail=" + dotID("cluster_"+t.From)
+	}
+	to, toComposite := entryState(t.To, children)
+	if toComposite {
+		attrs = attrs + " lhead=" + dotID("cluster_"+t.To)
+	}
+	return fmt.Sprintf(`%s -> %s [%s]`, dotID(from), dotID(to), attrs)
+}
+
+// dotID quotes a state as a DOT ID, so states like Wildcard or names with spaces are valid nodes.
+func dotID(state string) string {
+	return `"` + dotEscape(state) + `"`
+}
+
+// dotEscape escapes backslashes, quotes and newlines for a DOT quoted string.
+func dotEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// dotLegend renders the action descriptions as a legend cluster, it is empty when no description is configured.
+func (m *StateMachine) dotLegend() string {
+	if len(m.actionDescriptions) == 0 {
+		return ""
+	}
+
+	actions := make([]string, 0, len(m.actionDescriptions))
+	for action := range m.actionDescriptions {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+
+	var label string
+	for _, action := range actions {
+		label = label + fmt.Sprintf(`%s: %s\l`, dotEscape(action), dotEscape(m.actionDescriptions[action]))
+	}
+
+	return "\r\n" + fmt.Sprintf(`subgraph cluster_legend {
+	label="Actions"
+	legend [shape=box fixedsize=false style=solid label="%s"]
+}`, label)
+}
+
+// system runs the graphviz command with the dot source as stdin, the error includes the output of graphviz.
+func system(c string, dot string) error {
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command(`cmd`, `/C`, c)
+	} else {
+		cmd = exec.Command(`/bin/sh`, `-c`, c)
+	}
+	cmd.Stdin = strings.NewReader(dot)
+	out, err := cmd.CombinedOutput()
+	if err != nil && len(out) > 0 {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return err
+
+}
@@ -0,0 +1,375 @@
+package fsm
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteDot(t *testing.T) {
+	var dot strings.Builder
+	if err := initFSM().WriteDot(&dot); err != nil {
+		t.Fatalf("write dot err: %v", err)
+	}
+
+	s := dot.String()
+	if !strings.HasPrefix(s, "digraph StateMachine {") || !strings.HasSuffix(s, "}") {
+		t.Errorf("invalid digraph: %s", s)
+	}
+	if !strings.Contains(s, `"Locked" -> "Unlocked" [label="Coin | check"]`) {
+		t.Errorf("missing edge: %s", s)
+	}
+	if strings.Contains(s, "cluster_legend") {
+		t.Errorf("unexpected legend without action descriptions: %s", s)
+	}
+}
+
+func TestWriteDotLegend(t *testing.T) {
+	fsm := initFSM().With(WithActionDescriptions(map[string]string{
+		"check": "verify the coin",
+		"pass":  "let the customer through",
+	}))
+
+	var dot strings.Builder
+	if err := fsm.WriteDot(&dot); err != nil {
+		t.Fatalf("write dot err: %v", err)
+	}
+
+	s := dot.String()
+	if !strings.Contains(s, "subgraph cluster_legend {") {
+		t.Errorf("missing legend cluster: %s", s)
+	}
+	if !strings.Contains(s, `legend [shape=box fixedsize=false style=solid label="check: verify the coin\lpass: let the customer through\l"]`) {
+		t.Errorf("missing legend node: %s", s)
+	}
+}
+
+func TestExportNeighborhood(t *testing.T) {
+	fsm := NewStateMachine(nil,
+		Transition{From: "A", Event: "e1", To: "B", Action: "a1"},
+		Transition{From: "B", Event: "e2", To: "C", Action: "a2"},
+		Transition{From: "C", Event: "e3", To: "D", Action: "a3"},
+		Transition{From: "X", Event: "e4", To: "B", Action: "a4"},
+	)
+
+	var dot strings.Builder
+	if err := fsm.ExportNeighborhood(&dot, "B", 1); err != nil {
+		t.Fatalf("export err: %v", err)
+	}
+	s := dot.String()
+	for _, edge := range []string{`"A" -> "B"`, `"B" -> "C"`, `"X" -> "B"`} {
+		if !strings.Contains(s, edge) {
+			t.Errorf("missing edge %s: %s", edge, s)
+		}
+	}
+	if strings.Contains(s, `"C" -> "D"`) {
+		t.Errorf("unexpected edge C -> D with depth 1: %s", s)
+	}
+
+	dot.Reset()
+	if err := fsm.ExportNeighborhood(&dot, "B", 2); err != nil {
+		t.Fatalf("export err: %v", err)
+	}
+	if !strings.Contains(dot.String(), `"C" -> "D"`) {
+		t.Errorf("missing edge C -> D with depth 2: %s", dot.String())
+	}
+}
+
+func TestExportFiltered(t *testing.T) {
+	fsm := NewStateMachine(nil,
+		Transition{From: "Cart", Event: "Pay", To: "Paid", Action: "charge"},
+		Transition{From: "Cart", Event: "Pay", To: "Review", Action: "flag", Guard: hasCredit, GuardName: "suspicious"},
+		Transition{From: "Paid", Event: "Ship", To: "Shipped", Action: "ship"},
+	)
+
+	var dot strings.Builder
+	err := fsm.ExportFiltered(&dot, func(t Transition) bool {
+		return t.Guard == nil
+	})
+	if err != nil {
+		t.Fatalf("export err: %v", err)
+	}
+	s := dot.String()
+	for _, edge := range []string{`"Cart" -> "Paid"`, `"Paid" -> "Shipped"`} {
+		if !strings.Contains(s, edge) {
+			t.Errorf("missing edge %s: %s", edge, s)
+		}
+	}
+	if strings.Contains(s, "Review") {
+		t.Errorf("unexpected guarded edge: %s", s)
+	}
+}
+
+func TestWriteDotMultilineLabels(t *testing.T) {
+	fsm := initFSM().With(WithMultilineLabels(true))
+
+	var dot strings.Builder
+	if err := fsm.WriteDot(&dot); err != nil {
+		t.Fatalf("write dot err: %v", err)
+	}
+	if s := dot.String(); !strings.Contains(s, `"Locked" -> "Unlocked" [label="Coin\ncheck"]`) {
+		t.Errorf("expected a two-line label: %s", s)
+	}
+}
+
+func TestExportWithDetailsLayout(t *testing.T) {
+	outfile := filepath.Join(t.TempDir(), "state.png")
+	err := initFSM().ExportWithDetails(outfile, "png", "spring", "72", "")
+	if err == nil || !strings.Contains(err.Error(), `unknown graphviz layout "spring"`) {
+		t.Errorf("expected an unknown layout error, got %v", err)
+	}
+
+	if _, err := exec.LookPath("dot"); err != nil {
+		t.Skip("graphviz is not installed")
+	}
+	if err := initFSM().ExportWithDetails(outfile, "png", LayoutCirco, "72", ""); err != nil {
+		t.Errorf("export err: %v", err)
+	}
+}
+
+func TestWriteDotEscapesLabels(t *testing.T) {
+	fsm := NewStateMachine(nil,
+		Transition{From: "Idle", Event: `Greet "all"`, To: "Greeted", Action: `say "hi" | wave`},
+	)
+
+	var dot strings.Builder
+	if err := fsm.WriteDot(&dot); err != nil {
+		t.Fatalf("write dot err: %v", err)
+	}
+	want := `"Idle" -> "Greeted" [label="Greet \"all\" | say \"hi\" | wave"]`
+	if s := dot.String(); !strings.Contains(s, want) {
+		t.Errorf("expected the escaped label %s: %s", want, s)
+	}
+}
+
+func TestWriteDotDeprecated(t *testing.T) {
+	fsm := NewStateMachine(nil,
+		Transition{From: "Locked", Event: "Coin", To: "Unlocked", Action: "check"},
+		Transition{From: "Locked", Event: "Token", To: "Unlocked", Action: "check-token", Deprecated: true},
+	)
+
+	var dot strings.Builder
+	if err := fsm.WriteDot(&dot); err != nil {
+		t.Fatalf("write dot err: %v", err)
+	}
+	s := dot.String()
+	if !strings.Contains(s, `"Locked" -> "Unlocked" [label="Token | check-token" style=dashed color=grey]`) {
+		t.Errorf("deprecated edge should be dashed and grey: %s", s)
+	}
+	if !strings.Contains(s, `"Locked" -> "Unlocked" [label="Coin | check"]`) {
+		t.Errorf("edge should keep the default style: %s", s)
+	}
+}
+
+func TestWriteDotGuarded(t *testing.T) {
+	fsm := NewStateMachine(nil,
+		Transition{From: "Locked", Event: "Push", To: "Unlocked", Action: "pass-credit", Guard: hasCredit},
+		Transition{From: "Locked", Event: "Coin", To: "Unlocked", Action: "check", Condition: "coins > 0"},
+		Transition{From: "Locked", Event: "Push", To: "Locked", Action: "invalid-push"},
+	)
+
+	var dot strings.Builder
+	if err := fsm.WriteDot(&dot); err != nil {
+		t.Fatalf("write dot err: %v", err)
+	}
+	s := dot.String()
+	for _, edge := range []string{
+		`"Locked" -> "Unlocked" [label="Push | pass-credit" style=dashed]`,
+		`"Locked" -> "Unlocked" [label="Coin | check" style=dashed]`,
+		`"Locked" -> "Locked" [label="Push | invalid-push"]`,
+	} {
+		if !strings.Contains(s, edge) {
+			t.Errorf("expected the edge %s: %s", edge, s)
+		}
+	}
+}
+
+func TestWriteDotWeight(t *testing.T) {
+	fsm := NewStateMachine(nil,
+		Transition{From: "Locked", Event: "Coin", To: "Unlocked", Action: "check", Weight: 1000},
+		Transition{From: "Unlocked", Event: "Push", To: "Locked", Action: "pass", Weight: 1},
+		Transition{From: "Locked", Event: "Push", To: "Locked", Action: "invalid-push"},
+	)
+
+	var dot strings.Builder
+	if err := fsm.WriteDot(&dot); err != nil {
+		t.Fatalf("write dot err: %v", err)
+	}
+	s := dot.String()
+	for _, edge := range []string{
+		`"Locked" -> "Unlocked" [label="Coin | check" weight=1000 penwidth=10]`,
+		`"Unlocked" -> "Locked" [label="Push | pass" weight=1 penwidth=1]`,
+		`"Locked" -> "Locked" [label="Push | invalid-push"]`,
+	} {
+		if !strings.Contains(s, edge) {
+			t.Errorf("expected the edge %s: %s", edge, s)
+		}
+	}
+}
+
+func TestExportEmpty(t *testing.T) {
+	fsm := NewStateMachine(nil)
+
+	var dot strings.Builder
+	if err := fsm.WriteDot(&dot); err != nil {
+		t.Fatalf("write dot err: %v", err)
+	}
+	s := dot.String()
+	if !strings.HasPrefix(s, "digraph StateMachine {") || !strings.HasSuffix(s, "}") ||
+		strings.Count(s, "{") != strings.Count(s, "}") || strings.Contains(s, "->") {
+		t.Errorf("expected a valid empty digraph: %s", s)
+	}
+
+	if _, err := exec.LookPath("dot"); err != nil {
+		t.Skip("graphviz is not installed")
+	}
+	outfile := filepath.Join(t.TempDir(), "empty.png")
+	if err := fsm.Export(outfile); err != nil {
+		t.Fatalf("export err: %v", err)
+	}
+	if fi, err := os.Stat(outfile); err != nil || fi.Size() == 0 {
+		t.Errorf("expected a non-empty image, got %v, %v", fi, err)
+	}
+}
+
+func TestWriteDotStateParents(t *testing.T) {
+	fsm := NewStateMachine(nil,
+		Transition{From: "Off", Event: "Power", To: "On", Action: "boot"},
+		Transition{From: "Idle", Event: "Play", To: "Playing", Action: "play"},
+		Transition{From: "Playing", Event: "Pause", To: "Paused", Action: "pause"},
+		Transition{From: "On", Event: "Power", To: "Off", Action: "shutdown"},
+	).With(WithStateParents(map[string]string{
+		"Idle":    "On",
+		"Active":  "On",
+		"Playing": "Active",
+		"Paused":  "Active",
+	}))
+
+	var dot strings.Builder
+	if err := fsm.WriteDot(&dot); err != nil {
+		t.Fatalf("write dot err: %v", err)
+	}
+	s := dot.String()
+
+	on := strings.Index(s, `subgraph "cluster_On" {`)
+	active := strings.Index(s, `subgraph "cluster_Active" {`)
+	if on < 0 || active < on {
+		t.Fatalf("expected Active nested in On: %s", s)
+	}
+	if i := strings.Index(s[active:], `"Playing"`); i < 0 || i > strings.Index(s[active:], "}") {
+		t.Errorf("expected Playing in the Active cluster: %s", s)
+	}
+	if !strings.Contains(s, "compound=true") {
+		t.Errorf("expected a compound graph: %s", s)
+	}
+	for _, edge := range []string{
+		`"Off" -> "Paused" [label="Power | boot" lhead="cluster_On"]`,
+		`"Paused" -> "Off" [label="Power | shutdown" ltail="cluster_On"]`,
+		`"Playing" -> "Paused" [label="Pause | pause"]`,
+	} {
+		if !strings.Contains(s, edge) {
+			t.Errorf("missing edge %s: %s", edge, s)
+		}
+	}
+	if strings.Count(s, "{") != strings.Count(s, "}") {
+		t.Errorf("unbalanced braces: %s", s)
+	}
+}
+
+func TestWriteDotStateGroups(t *testing.T) {
+	fsm := NewStateMachine(nil,
+		Transition{From: "Idle", Event: "Dial", To: "Connecting", Action: "dial"},
+		Transition{From: "Connecting", Event: "Ack", To: "Connected", Action: "ack"},
+		Transition{From: "Connected", Event: "Hangup", To: "Idle", Action: "hangup"},
+	).With(WithStateGroups(map[string][]string{
+		"connection": {"Connecting", "Connected"},
+		"my group":   {"Idle"},
+		"unused":     {"Missing"},
+	}))
+
+	var dot strings.Builder
+	if err := fsm.WriteDot(&dot); err != nil {
+		t.Fatalf("write dot err: %v", err)
+	}
+	s := dot.String()
+	if !strings.Contains(s, "subgraph \"cluster_connection\" {\r\n\tlabel=\"connection\"\r\n\t\"Connecting\"\r\n\t\"Connected\"\r\n}") {
+		t.Errorf("missing cluster block: %s", s)
+	}
+	if !strings.Contains(s, "subgraph \"cluster_my group\" {\r\n\tlabel=\"my group\"\r\n\t\"Idle\"\r\n}") {
+		t.Errorf("missing quoted cluster block: %s", s)
+	}
+	if strings.Contains(s, "cluster_unused") {
+		t.Errorf("unexpected cluster without states: %s", s)
+	}
+}
+
+func TestExportHighlight(t *testing.T) {
+	var dot strings.Builder
+	if err := initFSM().ExportHighlight(&dot, "Unlocked"); err != nil {
+		t.Fatalf("export err: %v", err)
+	}
+	s := dot.String()
+	if !strings.Contains(s, `"Unlocked" [color=red penwidth=3 fontcolor=red]`) {
+		t.Errorf("missing highlighted node: %s", s)
+	}
+	if strings.Contains(s, `"Locked" [color=red`) {
+		t.Errorf("unexpected highlighted node: %s", s)
+	}
+}
+
+func TestWriteDotQuotesStates(t *testing.T) {
+	fsm := NewStateMachine(nil,
+		Transition{From: Wildcard, Event: "Reset", To: "Idle", Action: "reset"},
+		Transition{From: "Idle", Event: "Go", To: "Work \"hard\"", Action: "go"},
+	)
+
+	var dot strings.Builder
+	if err := fsm.ExportHighlight(&dot, Wildcard); err != nil {
+		t.Fatalf("write dot err: %v", err)
+	}
+	s := dot.String()
+	for _, want := range []string{
+		`"*" -> "Idle" [label="Reset | reset"]`,
+		`"Idle" -> "Work \"hard\"" [label="Go | go"]`,
+		`"*" [color=red penwidth=3 fontcolor=red]`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("missing %s: %s", want, s)
+		}
+	}
+}
+
+func TestWriteDotLegendEscaping(t *testing.T) {
+	fsm := initFSM().With(WithActionDescriptions(map[string]string{
+		"check": `say "hi" to C:\dir`,
+	}))
+
+	var dot strings.Builder
+	if err := fsm.WriteDot(&dot); err != nil {
+		t.Fatalf("write dot err: %v", err)
+	}
+	if !strings.Contains(dot.String(), `label="check: say \"hi\" to C:\\dir\l"`) {
+		t.Errorf("legend should be escaped: %s", dot.String())
+	}
+}
+
+func TestWriteDotStateActions(t *testing.T) {
+	fsm := initFSM()
+	fsm.StateEntry("Unlocked", "light-on")
+	fsm.StateExit("Unlocked", "light-off")
+
+	var buf strings.Builder
+	if err := fsm.WriteDot(&buf); err != nil {
+		t.Fatalf("write dot err: %v", err)
+	}
+	dot := buf.String()
+	want := `"Unlocked" [shape=record fixedsize=false label="{Unlocked|entry / light-on\lexit / light-off\l}"]`
+	if !strings.Contains(dot, want) {
+		t.Errorf("expected the record node %s, got %s", want, dot)
+	}
+	if strings.Contains(dot, `"Locked" [shape=record`) {
+		t.Errorf("expected no record node for Locked without state actions, got %s", dot)
+	}
+}
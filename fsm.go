@@ -8,18 +8,108 @@
 package fsm
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"os/exec"
-	"runtime"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// Wildcard matches any state when used as Transition.From and any event when used as Transition.Event.
+const Wildcard = "*"
+
+// Guard decides whether a transition can be taken. It receives the current state of the object, the triggered event
+// and the args of Trigger, so a guard can be shared by several transitions and events.
+type Guard func(from string, event string, args []interface{}) bool
+
+// ContextGuard is a Guard that receives a context, e.g. for guards doing I/O. ParallelMatcher cancels the context
+// when the result is no longer needed, other matchers pass context.Background().
+type ContextGuard func(ctx context.Context, from string, event string, args []interface{}) bool
+
 // Transition is a state transition and all data are literal values that simplifies FSM usage and make it generic.
+// From and Event can be Wildcard. When several transitions match, the most specific one wins:
+// exact From and Event, then exact From with wildcard Event, then wildcard From with exact Event, then both wildcards.
+// A transition with a Guard only matches when the guard passes.
 type Transition struct {
-	From   string
-	Event  string
-	To     string
-	Action string
+	From   string `json:"from"`
+	Event  string `json:"event"`
+	To     string `json:"to"`
+	Action string `json:"action,omitempty"`
+
+	// Froms are more source states of the transition, e.g. an event valid from several states but not all.
+	// NewStateMachine expands them into a transition per source state, together with From if it is not empty.
+	Froms []string `json:"froms,omitempty"`
+
+	// Guard is the condition of the transition, nil means unconditional.
+	Guard Guard `json:"-"`
+	// ContextGuard is a condition of the transition that receives a context, both Guard and ContextGuard must pass
+	// if both are set.
+	ContextGuard ContextGuard `json:"-"`
+	// GuardName names the guard for documentation.
+	GuardName string `json:"guard,omitempty"`
+	// Condition is a guard expression, e.g. "quantity > 0", compiled into Guard by package fsmexpr. A transition with
+	// a Condition but no Guard never matches, so a condition that is not compiled doesn't pass silently.
+	Condition string `json:"condition,omitempty"`
+	// OnGuardFail is the state the object enters when the guard rejects and no other transition matches,
+	// instead of failing with ErrNoTransition. OnExit and OnEnter run as usual, the transition has no action.
+	// A guard can pass the reason to the processor through the args, e.g. by setting a field of the object.
+	OnGuardFail string `json:"onGuardFail,omitempty"`
+	// OnFailure is the compensation state the object enters when the action fails, e.g. after a partial external
+	// effect. OnActionFailure is called as usual, then the object enters OnFailure and Trigger returns
+	// a *CompensationError. Empty means the object stays in From.
+	OnFailure string `json:"onFailure,omitempty"`
+
+	// Timeout makes the transition a timeout transition: its event is due when the object has been in From for
+	// the duration, see Watchdog. Zero means the event is only fired by the caller.
+	Timeout time.Duration `json:"timeout,omitempty"`
+
+	// Tags group transitions, e.g. by subsystem, so WithActiveTags can enable groups of transitions.
+	Tags []string `json:"tags,omitempty"`
+
+	// Weight is the importance of the transition in diagrams, e.g. its hit count of HitCounts: WriteDot draws
+	// heavier edges shorter and bolder. Zero means the default layout.
+	Weight int `json:"weight,omitempty"`
+
+	// Deprecated marks a transition kept for compatibility. Triggering it calls the deprecation handler, if any.
+	Deprecated bool `json:"deprecated,omitempty"`
+
+	// index is the position of the original transition in a lowercase copy made by WithCaseInsensitive.
+	index int
+}
+
+// String returns a compact form of the transition: From --(Event [GuardName]/Action)--> To.
+// GuardName and Action are omitted when they are empty.
+func (t Transition) String() string {
+	event := t.Event
+	if t.GuardName != "" {
+		event = fmt.Sprintf("%s [%s]", t.Event, t.GuardName)
+	}
+	if t.Action == "" {
+		return fmt.Sprintf("%s --(%s)--> %s", t.From, event, t.To)
+	}
+	return fmt.Sprintf("%s --(%s/%s)--> %s", t.From, event, t.Action, t.To)
+}
+
+// ChangesState reports whether the transition leaves its state, DefaultDelegate only calls OnExit and OnEnter for
+// such transitions unless an action redirects. A transition from Wildcard changes the state of objects in other states,
+// so it is reported as changing state; when it is triggered in its To state, EventContext.ChangesState reports
+// false and DefaultDelegate treats it as a self-transition.
+func (t Transition) ChangesState() bool {
+	return changesState(t.From, t.To)
+}
+
+// changesState reports whether an object in state from leaves it for to, the rule of Transition.ChangesState and
+// EventContext.ChangesState.
+func changesState(from string, to string) bool {
+	return from != to
+}
+
+// guarded reports whether the transition has a Guard or a ContextGuard.
+func (t Transition) guarded() bool {
+	return t.Guard != nil || t.ContextGuard != nil
 }
 
 // Delegate is used to process actions. Because gofsm uses literal values as event, state and action, you need to handle them with corresponding functions. DefaultDelegate is the default delegate implementation that splits the processing into three actions: OnExit Action, Action and OnEnter Action. you can implement different delegates.
@@ -32,6 +122,100 @@ type Delegate interface {
 type StateMachine struct {
 	delegate    Delegate
 	transitions []Transition
+
+	matcher            Matcher
+	clock              Clock
+	actionDescriptions map[string]string
+	stateGroups        map[string][]string
+	stateParents       map[string]string
+	multilineLabels    bool
+	activeTags         map[string]bool
+	eventNormalizer    func(event string) string
+	finalStates        map[string]bool
+	finalPredicate     func(state string, args []interface{}) bool
+	ignoredEvents      map[string]map[string]bool // state -> events without transition that are no-ops
+	actionParams       map[string]map[string]interface{}
+	entryActions       map[string]string
+	exitActions        map[string]string
+
+	firstSeen  func(state string)
+	seenStates sync.Map
+
+	deprecationHandler func(t Transition)
+	stateAccessor      StateAccessor
+	observers          []ContextObserver
+	afterTransition    Observer
+	stateMutator       StateMutator
+	seenStore          SeenStore
+	version            string
+	actionTimer        func(action string, d time.Duration)
+	argsTransformer    func(from, event, to string, args []interface{}) []interface{}
+	continueOnError    bool
+	folded             []Transition // transitions with lowercase From and Event, see WithCaseInsensitive
+
+	paused int32 // accessed atomically, see Pause
+
+	stats     bool
+	hitCounts sync.Map // From/Event -> *uint64, see WithStats
+
+	throttleMu     sync.Mutex
+	throttled      map[string]time.Time // key -> time until events for the key are dropped
+	throttleScanAt int
+}
+
+// ErrNoTransition is reported when there is no transition for the event in the current state,
+// errors returned by Trigger for this case satisfy errors.Is(err, ErrNoTransition).
+var ErrNoTransition = errors.New("state machine error: cannot find transition")
+
+// ErrNoDelegate is returned by Trigger for a transition with an action when the state machine has no delegate.
+// Transitions without action don't need a delegate.
+var ErrNoDelegate = errors.New("state machine error: no delegate configured")
+
+// ErrPaused is returned by Trigger while the state machine is paused, see Pause.
+var ErrPaused = errors.New("state machine error: paused")
+
+// MaxFollowUpEvents is the maximum number of follow-up events processed by one Trigger, it stops emit cycles.
+const MaxFollowUpEvents = 100
+
+// ErrTooManyFollowUps is reported when a Trigger emits more than MaxFollowUpEvents follow-up events.
+var ErrTooManyFollowUps = errors.New("state machine error: too many follow-up events")
+
+// FollowUpError is returned by Trigger when a follow-up event fails. It keeps the state the object reached,
+// since the transitions before the follow-up event have completed. It unwraps to the error of the follow-up event.
+type FollowUpError struct {
+	// Event is the follow-up event that failed.
+	Event string
+	// State is the state the object is in, the follow-up event was fired from it.
+	State string
+	// Err is the error of the follow-up event.
+	Err error
+}
+
+func (e *FollowUpError) Error() string {
+	return fmt.Sprintf("state machine error: follow-up event [%s] failed in state [%s]: %v", e.Event, e.State, e.Err)
+}
+
+func (e *FollowUpError) Unwrap() error {
+	return e.Err
+}
+
+// CompensationError is returned by Trigger when the action of a transition failed and the object entered the
+// OnFailure state of the transition instead. It unwraps to the error of the action.
+type CompensationError struct {
+	// Action is the action that failed.
+	Action string
+	// State is the OnFailure state the object entered.
+	State string
+	// Err is the error of the action.
+	Err error
+}
+
+func (e *CompensationError) Error() string {
+	return fmt.Sprintf("state machine error: action [%s] failed, compensated by entering state [%s]: %v", e.Action, e.State, e.Err)
+}
+
+func (e *CompensationError) Unwrap() error {
+	return e.Err
 }
 
 // Error is an error when processing event and state changing.
@@ -42,14 +226,24 @@ type Error interface {
 }
 
 type smError struct {
-	badEvent     string
-	currentState string
+	badEvent      string
+	currentState  string
+	allowedEvents []string
 }
 
 func (e smError) Error() string {
+	if len(e.allowedEvents) > 0 {
+		return fmt.Sprintf("state machine error: cannot find transition for event [%s] when in state [%s], allowed events: [%s]\n",
+			e.badEvent, e.currentState, strings.Join(e.allowedEvents, ", "))
+	}
 	return fmt.Sprintf("state machine error: cannot find transition for event [%s] when in state [%s]\n", e.badEvent, e.currentState)
 }
 
+// Is makes errors.Is(err, ErrNoTransition) true.
+func (e smError) Is(target error) bool {
+	return target == ErrNoTransition
+}
+
 func (e smError) BadEvent() string {
 	return e.badEvent
 }
@@ -60,67 +254,445 @@ func (e smError) CurrentState() string {
 
 // NewStateMachine creates a new state machine.
 func NewStateMachine(delegate Delegate, transitions ...Transition) *StateMachine {
-	return &StateMachine{delegate: delegate, transitions: transitions}
+	return &StateMachine{delegate: delegate, transitions: expandFroms(transitions), matcher: LinearMatcher{}, clock: RealClock{}}
+}
+
+// expandFroms replaces the transitions with Froms by a transition per source state. It always returns a new slice,
+// so options like WithEventNormalizer don't modify the transitions of the caller.
+func expandFroms(transitions []Transition) []Transition {
+	expanded := make([]Transition, 0, len(transitions))
+	for _, t := range transitions {
+		if len(t.Froms) == 0 {
+			expanded = append(expanded, t)
+			continue
+		}
+		froms := t.Froms
+		if t.From != "" {
+			froms = append([]string{t.From}, froms...)
+		}
+		for _, from := range froms {
+			e := t
+			e.From, e.Froms = from, nil
+			expanded = append(expanded, e)
+		}
+	}
+	return expanded
 }
 
 // Trigger fires a event. You must pass current state of the processing object, other info about this object can be passed with args.
+// If no transition matches, the error is an Error that matches ErrNoTransition. Errors of the delegate are returned as they are.
+// Follow-up events emitted through EventContext are processed before Trigger returns, see EventContext.Emit.
+// If a follow-up event fails, the error is a *FollowUpError with the state the object reached.
 func (m *StateMachine) Trigger(currentState string, event string, args ...interface{}) error {
-	trans := m.findTransMatching(currentState, event)
+	_, err := m.trigger(currentState, event, args, m.observe(context.Background(), nil))
+	return err
+}
+
+// TriggerMatched fires a event like Trigger and reports whether a transition matched the event, e.g. to tell
+// a transition without action, which succeeds without calling the delegate, from an event ignored by
+// WithIgnoredEvents. matched is also true if the action of the matched transition failed.
+func (m *StateMachine) TriggerMatched(currentState string, event string, args ...interface{}) (matched bool, err error) {
+	_, err = m.trigger(currentState, event, args, m.observe(context.Background(), func(c *EventContext, err error) {
+		matched = true
+	}))
+	return matched, err
+}
+
+// trigger fires the event and its follow-up events, it returns the state the object ends in.
+// observe is called, if not nil, after the delegate has processed each matched transition.
+func (m *StateMachine) trigger(currentState string, event string, args []interface{}, observe func(c *EventContext, err error)) (string, error) {
+	return m.triggerWith(m.delegate, currentState, event, args, observe)
+}
+
+// triggerWith is trigger with the delegate d instead of the delegate of the state machine.
+func (m *StateMachine) triggerWith(d Delegate, currentState string, event string, args []interface{}, observe func(c *EventContext, err error)) (string, error) {
+	if m.Paused() {
+		return currentState, ErrPaused
+	}
+	var pending []string
+	state := currentState
+	for followUps := 0; ; followUps++ {
+		if followUps > MaxFollowUpEvents {
+			return state, &FollowUpError{Event: event, State: state, Err: ErrTooManyFollowUps}
+		}
+
+		var emitted []string
+		to, updated, err := m.fire(d, state, event, args, func(event string) {
+			emitted = append(emitted, event)
+		}, observe)
+		if err != nil {
+			if followUps > 0 {
+				err = &FollowUpError{Event: event, State: to, Err: err}
+			}
+			return to, err
+		}
+
+		pending = append(emitted, pending...)
+		if len(pending) == 0 {
+			return to, nil
+		}
+		state, event, args, pending = to, pending[0], updated, pending[1:]
+	}
+}
+
+// fire processes a single transition and returns the state it entered with the args updated by the action.
+func (m *StateMachine) fire(delegate Delegate, currentState string, event string, args []interface{}, emit func(event string), observe func(c *EventContext, err error)) (string, []interface{}, error) {
+	trans := m.findTransMatching(currentState, event, args)
+	routed := false
+	if trans == nil {
+		trans = m.guardFailRoute(currentState, event)
+		routed = trans != nil
+	}
+	if trans == nil && m.ignored(currentState, event) {
+		return currentState, args, nil
+	}
 	if trans == nil {
-		return smError{event, currentState}
+		return currentState, args, smError{event, currentState, m.AvailableEvents(currentState)}
+	}
+
+	if m.stats {
+		m.countHit(*trans)
+	}
+	if trans.Deprecated && m.deprecationHandler != nil {
+		m.deprecationHandler(*trans)
+	}
+	if m.argsTransformer != nil {
+		args = m.argsTransformer(currentState, event, trans.To, args)
 	}
 
+	c := &EventContext{Event: event, Action: trans.Action, From: currentState, To: trans.To, Args: args,
+		OnFailure: trans.OnFailure, Params: copyParams(m.actionParams[trans.Action]), emit: emit, entryActions: m.entryActions, exitActions: m.exitActions,
+		clock: m.clock, actionTimer: m.actionTimer, continueOnActionError: m.continueOnError}
 	var err error
-	if trans.Action != "" {
-		err = m.delegate.HandleEvent(trans.Action, currentState, trans.To, args)
+	if routed || trans.Action != "" || m.exitActions[currentState] != "" || m.entryActions[trans.To] != "" {
+		if delegate == nil {
+			err = ErrNoDelegate
+		} else if d, ok := delegate.(ContextDelegate); ok {
+			err = d.HandleEventContext(c)
+		} else {
+			err = delegate.HandleEvent(trans.Action, currentState, trans.To, args)
+			if err != nil && trans.OnFailure != "" {
+				err = compensate(delegate, c, err)
+			}
+		}
 	}
-	return err
+	if err == nil && c.To == "" {
+		c.To = trans.To
+	}
+	if observe != nil {
+		observe(c, err)
+	}
+	var compensated *CompensationError
+	if errors.As(err, &compensated) {
+		return compensated.State, c.Args, err
+	}
+	if err != nil {
+		return currentState, c.Args, err
+	}
+	if m.afterTransition != nil {
+		m.afterTransition(c.From, c.Event, c.To, c.Action, c.Args)
+	}
+
+	if m.firstSeen != nil {
+		if _, seen := m.seenStates.LoadOrStore(c.To, struct{}{}); !seen {
+			m.firstSeen(c.To)
+		}
+	}
+	return c.To, c.Args, nil
 }
 
-// findTransMatching gets corresponding transition according to current state and event.
-func (m *StateMachine) findTransMatching(fromState string, event string) *Transition {
-	for _, v := range m.transitions {
-		if v.From == fromState && v.Event == event {
-			return &v
+// countHit increments the hit counter of the matched transition.
+func (m *StateMachine) countHit(t Transition) {
+	key := t.From + "/" + t.Event
+	n, ok := m.hitCounts.Load(key)
+	if !ok {
+		n, _ = m.hitCounts.LoadOrStore(key, new(uint64))
+	}
+	atomic.AddUint64(n.(*uint64), 1)
+}
+
+// HitCounts returns how often the transitions matched since WithStats was enabled, keyed by "From/Event" of the
+// transitions, e.g. to find the dominant paths or to order transitions for LinearMatcher. Transitions that never
+// matched are omitted. It is safe for concurrent use.
+func (m *StateMachine) HitCounts() map[string]uint64 {
+	counts := make(map[string]uint64)
+	m.hitCounts.Range(func(key, value interface{}) bool {
+		counts[key.(string)] = atomic.LoadUint64(value.(*uint64))
+		return true
+	})
+	return counts
+}
+
+// Pause makes Trigger reject all events with ErrPaused until Resume is called, e.g. during a maintenance window.
+// The delegate is not called while paused, transitions in progress complete. It is safe for concurrent use.
+func (m *StateMachine) Pause() {
+	atomic.StoreInt32(&m.paused, 1)
+}
+
+// Resume makes Trigger process events again after Pause.
+func (m *StateMachine) Resume() {
+	atomic.StoreInt32(&m.paused, 0)
+}
+
+// Paused reports whether the state machine is paused.
+func (m *StateMachine) Paused() bool {
+	return atomic.LoadInt32(&m.paused) != 0
+}
+
+// compensate enters the OnFailure state of the context through a delegate d that is not a ContextDelegate, like a
+// transition without action, after its action failed with err.
+func compensate(d Delegate, c *EventContext, err error) error {
+	if cerr := d.HandleEvent("", c.From, c.OnFailure, c.Args); cerr != nil {
+		return err
+	}
+	c.To = c.OnFailure
+	return &CompensationError{Action: c.Action, State: c.OnFailure, Err: err}
+}
+
+// guardFailRoute returns the transition to the OnGuardFail state of the most specific guarded transition for the
+// state and event, it is only called when no transition matched, so the guard has rejected.
+func (m *StateMachine) guardFailRoute(currentState string, event string) *Transition {
+	event = m.normalizeEvent(event)
+	var best *Transition
+	bestRank := 0
+	transitions := m.activeTransitions()
+	if m.folded != nil {
+		currentState, event = strings.ToLower(currentState), strings.ToLower(event)
+		transitions = m.active(m.folded)
+	}
+	for i := range transitions {
+		t := transitions[i]
+		if !t.guarded() || t.OnGuardFail == "" {
+			continue
+		}
+		if rank := matchRank(t, currentState, event); rank > bestRank {
+			best = &Transition{From: t.From, Event: t.Event, To: t.OnGuardFail, GuardName: t.GuardName}
+			bestRank = rank
+		}
+	}
+	return best
+}
+
+// Transitions returns a copy of the transitions, e.g. to create a machine with a local delegate from a decoded one.
+func (m *StateMachine) Transitions() []Transition {
+	return append([]Transition(nil), m.transitions...)
+}
+
+// Walk calls visit for each transition in the order they are defined, e.g. for custom exporters and reports.
+// It stops at the first error of visit and returns it.
+func (m *StateMachine) Walk(visit func(t Transition) error) error {
+	for _, t := range m.transitions {
+		if err := visit(t); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
-// Export exports the state diagram into a file.
-func (m *StateMachine) Export(outfile string) error {
-	return m.ExportWithDetails(outfile, "png", "dot", "72", "-Gsize=10,5 -Gdpi=200")
+// FindTransition returns a copy of the transition that would be processed for the current state and event without
+// processing it. It follows the same matching rules as Trigger.
+// Guards are evaluated with args.
+func (m *StateMachine) FindTransition(currentState string, event string, args ...interface{}) (*Transition, bool) {
+	trans := m.findTransMatching(currentState, event, args)
+	return trans, trans != nil
 }
 
-// ExportWithDetails  exports the state diagram with more graphviz options.
-func (m *StateMachine) ExportWithDetails(outfile string, format string, layout string, scale string, more string) error {
-	dot := `digraph StateMachine {
+// Describe returns the transitions formatted by Transition.String in sorted order, e.g. to dump the table to logs.
+func (m *StateMachine) Describe() []string {
+	var lines []string
+	m.Walk(func(t Transition) error {
+		lines = append(lines, t.String())
+		return nil
+	})
+	sort.Strings(lines)
+	return lines
+}
 
-	rankdir=LR
-	node[width=1 fixedsize=true shape=circle style=filled fillcolor="darkorchid1" ]
-	
-	`
+// ActionFor returns the action of the transition that would be processed for the state and event, e.g. to build
+// reports mapping events to actions. Guards are evaluated without args. It returns false if no transition matches,
+// and an empty action if the matching transition has none.
+func (m *StateMachine) ActionFor(from string, event string) (string, bool) {
+	trans, ok := m.FindTransition(from, event)
+	if !ok {
+		return "", false
+	}
+	return trans.Action, true
+}
 
-	for _, t := range m.transitions {
-		link := fmt.Sprintf(`%s -> %s [label="%s | %s"]`, t.From, t.To, t.Event, t.Action)
-		dot = dot + "\r\n" + link
+// findTransMatching gets corresponding transition according to current state and event with the configured Matcher.
+// It returns a copy of the transition.
+func (m *StateMachine) findTransMatching(fromState string, event string, args []interface{}) *Transition {
+	if m.folded != nil {
+		return m.findFolded(fromState, event, args)
+	}
+	best := m.match(m.activeTransitions(), fromState, m.normalizeEvent(event), args)
+	if best == nil {
+		return nil
 	}
 
-	dot = dot + "\r\n}"
-	cmd := fmt.Sprintf("dot -o%s -T%s -K%s -s%s %s", outfile, format, layout, scale, more)
+	t := *best
+	return &t
+}
+
+// match finds the transition with the matcher, a ContextMatcher gets a context with a new cache for CachedGuard.
+func (m *StateMachine) match(transitions []Transition, fromState string, event string, args []interface{}) *Transition {
+	if cm, ok := m.matcher.(ContextMatcher); ok {
+		return cm.MatchContext(withGuardCache(context.Background()), transitions, fromState, event, args)
+	}
+	return m.matcher.Match(transitions, fromState, event, args)
+}
 
-	return system(cmd, dot)
+// findFolded matches the lowercase state and event against the folded transitions for WithCaseInsensitive and
+// returns a copy of the original transition, found by the index the match carries, so matchers may return copies.
+func (m *StateMachine) findFolded(fromState string, event string, args []interface{}) *Transition {
+	best := m.match(m.active(m.folded), strings.ToLower(fromState), strings.ToLower(m.normalizeEvent(event)), args)
+	if best == nil {
+		return nil
+	}
+	t := m.transitions[best.index]
+	return &t
 }
 
-func system(c string, dot string) error {
+// ignored reports whether the event is ignored in the state, see WithIgnoredEvents.
+func (m *StateMachine) ignored(state string, event string) bool {
+	state, event = m.ignoredKey(state, event)
+	return m.ignoredEvents[state][event]
+}
 
-	var cmd *exec.Cmd
-	if runtime.GOOS == "windows" {
-		cmd = exec.Command(`cmd`, `/C`, c)
-	} else {
-		cmd = exec.Command(`/bin/sh`, `-c`, c)
+// ignoredKey returns the state and event as they are matched: the event normalized and both lowercase with
+// WithCaseInsensitive.
+func (m *StateMachine) ignoredKey(state string, event string) (string, string) {
+	event = m.normalizeEvent(event)
+	if m.folded != nil {
+		return strings.ToLower(state), strings.ToLower(event)
 	}
-	cmd.Stdin = strings.NewReader(dot)
-	return cmd.Run()
+	return state, event
+}
 
+// normalizeEvent normalizes the event for matching with the normalizer of WithEventNormalizer, if any.
+func (m *StateMachine) normalizeEvent(event string) string {
+	if m.eventNormalizer == nil || event == Wildcard {
+		return event
+	}
+	return m.eventNormalizer(event)
+}
+
+// activeTransitions returns the transitions enabled by WithActiveTags: all transitions if no tag is active, otherwise
+// the untagged transitions and the transitions with an active tag.
+func (m *StateMachine) activeTransitions() []Transition {
+	return m.active(m.transitions)
+}
+
+// active filters the transitions enabled by WithActiveTags.
+func (m *StateMachine) active(transitions []Transition) []Transition {
+	if len(m.activeTags) == 0 {
+		return transitions
+	}
+
+	var active []Transition
+	for _, t := range transitions {
+		enabled := len(t.Tags) == 0
+		for _, tag := range t.Tags {
+			enabled = enabled || m.activeTags[tag]
+		}
+		if enabled {
+			active = append(active, t)
+		}
+	}
+	return active
+}
+
+// AvailableEvents returns the distinct events that have a transition from the state, in definition order.
+// Guards are not evaluated, and transitions from Wildcard are included. Transitions disabled by WithActiveTags are not.
+func (m *StateMachine) AvailableEvents(state string) []string {
+	var events []string
+	seen := make(map[string]bool)
+	for _, v := range m.activeTransitions() {
+		if (v.From == state || v.From == Wildcard || m.folded != nil && strings.EqualFold(v.From, state)) && !seen[v.Event] {
+			seen[v.Event] = true
+			events = append(events, v.Event)
+		}
+	}
+	return events
+}
+
+// RegisterActionParams registers the configuration of an action, e.g. the template name of a "send-email" action,
+// so transitions reference the action by name only. The params are passed to ContextDelegate and ContextProcessor
+// through EventContext.Params. Like transitions, params must be registered before use.
+func (m *StateMachine) RegisterActionParams(action string, params map[string]interface{}) {
+	if m.actionParams == nil {
+		m.actionParams = make(map[string]map[string]interface{})
+	}
+	m.actionParams[action] = params
+}
+
+// StateEntry registers the entry action of the state. DefaultDelegate runs it through the processor like a transition
+// action whenever the state is entered, after the action of the transition. Like transitions, state actions must be
+// registered before use.
+func (m *StateMachine) StateEntry(state string, action string) {
+	if m.entryActions == nil {
+		m.entryActions = make(map[string]string)
+	}
+	m.entryActions[state] = action
+}
+
+// StateExit registers the exit action of the state. DefaultDelegate runs it through the processor like a transition
+// action whenever the state is left, before the action of the transition.
+func (m *StateMachine) StateExit(state string, action string) {
+	if m.exitActions == nil {
+		m.exitActions = make(map[string]string)
+	}
+	m.exitActions[state] = action
+}
+
+// copyParams copies the registered params so an action can't change them for the following transitions.
+func copyParams(params map[string]interface{}) map[string]interface{} {
+	if params == nil {
+		return nil
+	}
+	c := make(map[string]interface{}, len(params))
+	for k, v := range params {
+		c[k] = v
+	}
+	return c
+}
+
+// Guards returns the distinct guard names of the transitions in definition order, e.g. to document decision points.
+func (m *StateMachine) Guards() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, t := range m.transitions {
+		if t.GuardName != "" && !seen[t.GuardName] {
+			seen[t.GuardName] = true
+			names = append(names, t.GuardName)
+		}
+	}
+	return names
+}
+
+// Resetter is an optional interface of delegates and processors that are informed when the state machine is reset,
+// e.g. to clear their caches.
+type Resetter interface {
+	OnReset()
+}
+
+// ArgsResetter is like Resetter, but OnReset receives the args passed to Reset.
+type ArgsResetter interface {
+	OnReset(args []interface{})
+}
+
+// Reset informs the delegate that the whole system is reset, it calls OnReset if the delegate implements
+// Resetter or ArgsResetter. DefaultDelegate and the processor wrappers forward it to their processors.
+func (m *StateMachine) Reset(args ...interface{}) {
+	reset(m.delegate, args)
+}
+
+// reset calls OnReset of v if it implements Resetter or ArgsResetter.
+func reset(v interface{}, args []interface{}) {
+	switch r := v.(type) {
+	case Resetter:
+		r.OnReset()
+	case ArgsResetter:
+		r.OnReset(args)
+	}
 }
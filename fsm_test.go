@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"log"
 	"math/rand"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -152,3 +154,1001 @@ func initFSM() *StateMachine {
 
 	return NewStateMachine(delegate, transitions...)
 }
+
+func TestTransitionString(t *testing.T) {
+	tr := Transition{From: "Locked", Event: "Coin", To: "Unlocked", Action: "check"}
+	if s := tr.String(); s != "Locked --(Coin/check)--> Unlocked" {
+		t.Errorf("unexpected string: %s", s)
+	}
+
+	tr.Action = ""
+	if s := tr.String(); s != "Locked --(Coin)--> Unlocked" {
+		t.Errorf("unexpected string: %s", s)
+	}
+}
+
+func TestErrorAllowedEvents(t *testing.T) {
+	fsm := initFSM()
+
+	err := fsm.Trigger("Locked", "Kick")
+	if err == nil {
+		t.Fatal("expected an error for an unknown event")
+	}
+	if !strings.Contains(err.Error(), "allowed events: [Coin, Push]") {
+		t.Errorf("error should list allowed events: %v", err)
+	}
+
+	err = fsm.Trigger("Broken", "Kick")
+	if err == nil || strings.Contains(err.Error(), "allowed events") {
+		t.Errorf("error should not list allowed events for a state without transitions: %v", err)
+	}
+}
+
+// recordingProcessor records every callback it receives.
+type recordingProcessor struct {
+	calls     []string
+	emits     map[string][]string // action -> events emitted by the action
+	redirects map[string]string   // action -> state the action redirects to
+	events    []string            // events received by ActionContext
+}
+
+func (p *recordingProcessor) OnExit(fromState string, args []interface{}) {
+	p.calls = append(p.calls, "exit:"+fromState)
+}
+
+func (p *recordingProcessor) Action(action string, fromState string, toState string, args []interface{}) error {
+	p.calls = append(p.calls, "action:"+action)
+	return nil
+}
+
+func (p *recordingProcessor) ActionContext(c *EventContext) error {
+	p.calls = append(p.calls, "action:"+c.Action)
+	p.events = append(p.events, c.Event)
+	for _, e := range p.emits[c.Action] {
+		c.Emit(e)
+	}
+	if to, ok := p.redirects[c.Action]; ok {
+		c.To = to
+	}
+	return nil
+}
+
+func (p *recordingProcessor) OnActionFailure(action string, fromState string, toState string, args []interface{}, err error) {
+	p.calls = append(p.calls, "failure:"+action)
+}
+
+func (p *recordingProcessor) OnEnter(toState string, args []interface{}) {
+	p.calls = append(p.calls, "enter:"+toState)
+}
+
+func TestTriggerEmit(t *testing.T) {
+	p := &recordingProcessor{emits: map[string][]string{"prepare": {"AutoStart"}}}
+	fsm := NewStateMachine(&DefaultDelegate{P: p},
+		Transition{From: "Idle", Event: "Start", To: "Ready", Action: "prepare"},
+		Transition{From: "Ready", Event: "AutoStart", To: "Running", Action: "run"},
+	)
+
+	if err := fsm.Trigger("Idle", "Start"); err != nil {
+		t.Fatalf("trigger err: %v", err)
+	}
+
+	want := "[exit:Idle action:prepare enter:Ready exit:Ready action:run enter:Running]"
+	if got := fmt.Sprint(p.calls); got != want {
+		t.Errorf("expected calls %s, got %s", want, got)
+	}
+}
+
+func TestTriggerEmitDepthFirst(t *testing.T) {
+	p := &recordingProcessor{emits: map[string][]string{
+		"a": {"E1", "E2"},
+		"b": {"E3"},
+	}}
+	fsm := NewStateMachine(&DefaultDelegate{P: p},
+		Transition{From: "S0", Event: "Go", To: "S1", Action: "a"},
+		Transition{From: "S1", Event: "E1", To: "S2", Action: "b"},
+		Transition{From: "S2", Event: "E3", To: "S3", Action: "c"},
+		Transition{From: "S3", Event: "E2", To: "S4", Action: "d"},
+	)
+
+	if err := fsm.Trigger("S0", "Go"); err != nil {
+		t.Fatalf("trigger err: %v", err)
+	}
+	if p.calls[len(p.calls)-1] != "enter:S4" {
+		t.Errorf("expected to end in S4, got calls %v", p.calls)
+	}
+}
+
+func TestTriggerEmitFailure(t *testing.T) {
+	p := &recordingProcessor{emits: map[string][]string{"prepare": {"Missing"}}}
+	fsm := NewStateMachine(&DefaultDelegate{P: p},
+		Transition{From: "Idle", Event: "Start", To: "Ready", Action: "prepare"},
+	)
+
+	err := fsm.Trigger("Idle", "Start")
+	var fe *FollowUpError
+	if !errors.As(err, &fe) {
+		t.Fatalf("expected a FollowUpError, got %v", err)
+	}
+	if fe.State != "Ready" || fe.Event != "Missing" {
+		t.Errorf("expected Missing to fail in Ready, got %q in %q", fe.Event, fe.State)
+	}
+	if !errors.Is(err, ErrNoTransition) {
+		t.Errorf("expected the error to unwrap to ErrNoTransition, got %v", err)
+	}
+}
+
+func TestTriggerEmitCycle(t *testing.T) {
+	p := &recordingProcessor{emits: map[string][]string{"ping": {"Ping"}}}
+	fsm := NewStateMachine(&DefaultDelegate{P: p},
+		Transition{From: "S", Event: "Ping", To: "S", Action: "ping"},
+	)
+
+	if err := fsm.Trigger("S", "Ping"); !errors.Is(err, ErrTooManyFollowUps) {
+		t.Errorf("expected ErrTooManyFollowUps, got %v", err)
+	}
+}
+
+func TestWithFirstSeen(t *testing.T) {
+	var mu sync.Mutex
+	seen := make(map[string]int)
+	fsm := initFSM().With(WithFirstSeen(func(state string) {
+		mu.Lock()
+		seen[state]++
+		mu.Unlock()
+	}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(id uint64) {
+			defer wg.Done()
+			ts := &Turnstile{ID: id, State: "Locked"}
+			fsm.Trigger(ts.State, "Coin", ts)
+			fsm.Trigger(ts.State, "Push", ts)
+		}(uint64(i))
+	}
+	wg.Wait()
+
+	if seen["Unlocked"] != 1 || seen["Locked"] != 1 || len(seen) != 2 {
+		t.Errorf("expected each state to be seen once, got %v", seen)
+	}
+}
+
+func TestWildcardPrecedence(t *testing.T) {
+	fsm := NewStateMachine(nil,
+		Transition{From: Wildcard, Event: Wildcard, To: "AnyAny"},
+		Transition{From: Wildcard, Event: "Reset", To: "AnyReset"},
+		Transition{From: "Locked", Event: Wildcard, To: "LockedAny"},
+		Transition{From: "Locked", Event: "Coin", To: "LockedCoin"},
+		Transition{From: "Unlocked", Event: "Reset", To: "UnlockedReset"},
+	)
+
+	tests := []struct {
+		state string
+		event string
+		want  string
+	}{
+		{"Locked", "Coin", "LockedCoin"},
+		{"Locked", "Reset", "LockedAny"},
+		{"Locked", "Push", "LockedAny"},
+		{"Unlocked", "Reset", "UnlockedReset"},
+		{"Broken", "Reset", "AnyReset"},
+		{"Unlocked", "Push", "AnyAny"},
+		{"Broken", "Push", "AnyAny"},
+	}
+
+	for _, tt := range tests {
+		trans := fsm.findTransMatching(tt.state, tt.event, nil)
+		if trans == nil || trans.To != tt.want {
+			t.Errorf("%s/%s: expected %s, got %v", tt.state, tt.event, tt.want, trans)
+		}
+	}
+}
+
+func TestWildcardEventDelegate(t *testing.T) {
+	p := &recordingProcessor{}
+	fsm := NewStateMachine(&DefaultDelegate{P: p},
+		Transition{From: "Locked", Event: "Coin", To: "Unlocked", Action: "check"},
+		Transition{From: "Locked", Event: Wildcard, To: "Error", Action: "unexpected"},
+	)
+
+	if err := fsm.Trigger("Locked", "Kick"); err != nil {
+		t.Fatalf("trigger err: %v", err)
+	}
+	if fmt.Sprint(p.events) != "[Kick]" {
+		t.Errorf("expected the delegate to receive the real event, got %v", p.events)
+	}
+
+	want := "[exit:Locked action:unexpected enter:Error]"
+	if got := fmt.Sprint(p.calls); got != want {
+		t.Errorf("expected calls %s, got %s", want, got)
+	}
+}
+
+func TestDeprecatedTransition(t *testing.T) {
+	var deprecated []Transition
+	p := &recordingProcessor{}
+	fsm := NewStateMachine(&DefaultDelegate{P: p},
+		Transition{From: "Locked", Event: "Coin", To: "Unlocked", Action: "check"},
+		Transition{From: "Locked", Event: "Token", To: "Unlocked", Action: "check-token", Deprecated: true},
+	).With(WithDeprecationHandler(func(t Transition) {
+		deprecated = append(deprecated, t)
+	}))
+
+	if err := fsm.Trigger("Locked", "Coin"); err != nil {
+		t.Fatalf("trigger err: %v", err)
+	}
+	if len(deprecated) != 0 {
+		t.Errorf("unexpected deprecation callback: %v", deprecated)
+	}
+
+	if err := fsm.Trigger("Locked", "Token"); err != nil {
+		t.Fatalf("trigger err: %v", err)
+	}
+	if len(deprecated) != 1 || deprecated[0].Event != "Token" {
+		t.Errorf("expected the deprecation callback for Token, got %v", deprecated)
+	}
+	if p.calls[len(p.calls)-1] != "enter:Unlocked" {
+		t.Errorf("expected the deprecated transition to proceed, got %v", p.calls)
+	}
+}
+
+func TestFindTransition(t *testing.T) {
+	fsm := initFSM()
+
+	trans, ok := fsm.FindTransition("Locked", "Coin")
+	if !ok || trans.To != "Unlocked" || trans.Action != "check" {
+		t.Errorf("unexpected transition: %v", trans)
+	}
+
+	trans.To = "Broken"
+	if again, _ := fsm.FindTransition("Locked", "Coin"); again.To != "Unlocked" {
+		t.Errorf("FindTransition should return a copy, got %v", again)
+	}
+
+	if trans, ok := fsm.FindTransition("Locked", "Kick"); ok || trans != nil {
+		t.Errorf("expected no transition, got %v", trans)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	fsm := initFSM()
+
+	var visited []string
+	err := fsm.Walk(func(t Transition) error {
+		visited = append(visited, t.Action)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk err: %v", err)
+	}
+	want := "[check invalid-push pass repeat-check]"
+	if got := fmt.Sprint(visited); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+
+	stop := errors.New("stop")
+	visited = nil
+	err = fsm.Walk(func(t Transition) error {
+		visited = append(visited, t.Action)
+		if t.Action == "invalid-push" {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || len(visited) != 2 {
+		t.Errorf("expected the walk to stop with the visitor error, got %v after %v", err, visited)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	want := "[Locked --(Coin/check)--> Unlocked Locked --(Push/invalid-push)--> Locked Unlocked --(Coin/repeat-check)--> Unlocked Unlocked --(Push/pass)--> Locked]"
+	if got := fmt.Sprint(initFSM().Describe()); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+func TestActionFor(t *testing.T) {
+	fsm := initFSM()
+
+	if action, ok := fsm.ActionFor("Locked", "Coin"); !ok || action != "check" {
+		t.Errorf("expected action check, got %q %v", action, ok)
+	}
+	if action, ok := fsm.ActionFor("Locked", "Kick"); ok || action != "" {
+		t.Errorf("expected no action, got %q %v", action, ok)
+	}
+}
+
+func TestActionRedirect(t *testing.T) {
+	var seen []string
+	p := &recordingProcessor{
+		redirects: map[string]string{"compute": "Large"},
+		emits:     map[string][]string{"compute": {"Next"}},
+	}
+	fsm := NewStateMachine(&DefaultDelegate{P: p},
+		Transition{From: "Start", Event: "Compute", To: "Small", Action: "compute"},
+		Transition{From: "Large", Event: "Next", To: "Done", Action: "finish"},
+	).With(WithFirstSeen(func(state string) {
+		seen = append(seen, state)
+	}))
+
+	if err := fsm.Trigger("Start", "Compute"); err != nil {
+		t.Fatalf("trigger err: %v", err)
+	}
+
+	want := "[exit:Start action:compute enter:Large exit:Large action:finish enter:Done]"
+	if got := fmt.Sprint(p.calls); got != want {
+		t.Errorf("expected calls %s, got %s", want, got)
+	}
+	if fmt.Sprint(seen) != "[Large Done]" {
+		t.Errorf("expected the redirected state to be reported, got %v", seen)
+	}
+}
+
+// resetProcessor counts resets.
+type resetProcessor struct {
+	recordingProcessor
+	resets int
+}
+
+func (p *resetProcessor) OnReset() {
+	p.resets++
+}
+
+// argsResetProcessor records the args of resets.
+type argsResetProcessor struct {
+	recordingProcessor
+	args []interface{}
+}
+
+func (p *argsResetProcessor) OnReset(args []interface{}) {
+	p.args = args
+}
+
+func TestReset(t *testing.T) {
+	p := &resetProcessor{}
+	fsm := NewStateMachine(&DefaultDelegate{P: p}, initFSM().transitions...)
+
+	fsm.Reset()
+	if p.resets != 1 {
+		t.Errorf("expected OnReset to fire once, got %d", p.resets)
+	}
+
+	ap := &argsResetProcessor{}
+	NewStateMachine(&DefaultDelegate{P: ap}).Reset("all")
+	if fmt.Sprint(ap.args) != "[all]" {
+		t.Errorf("expected the args of Reset, got %v", ap.args)
+	}
+
+	// delegates without OnReset and nil delegates are ignored
+	initFSM().Reset()
+	NewStateMachine(nil).Reset()
+}
+
+func TestResetWrapped(t *testing.T) {
+	first, second := &resetProcessor{}, &resetProcessor{}
+	recorder := NewHistoryRecorder(CompositeProcessor{first, &recordingProcessor{}, second}, func(string, []interface{}) {})
+	NewStateMachine(&DefaultDelegate{P: recorder}).Reset()
+
+	if first.resets != 1 || second.resets != 1 {
+		t.Errorf("expected OnReset to be forwarded through the wrappers, got %d and %d", first.resets, second.resets)
+	}
+}
+
+// actionError is a typed error returned by actions.
+type actionError struct {
+	action string
+}
+
+func (e *actionError) Error() string {
+	return "action " + e.action + " failed"
+}
+
+// failingProcessor fails every action.
+type failingProcessor struct {
+	recordingProcessor
+}
+
+func (p *failingProcessor) ActionContext(c *EventContext) error {
+	return fmt.Errorf("wrapped: %w", &actionError{c.Action})
+}
+
+func TestErrorsIsAs(t *testing.T) {
+	fsm := NewStateMachine(&DefaultDelegate{P: &failingProcessor{}}, initFSM().transitions...)
+
+	err := fsm.Trigger("Locked", "Kick")
+	if !errors.Is(err, ErrNoTransition) {
+		t.Errorf("expected ErrNoTransition, got %v", err)
+	}
+	var fsmErr Error
+	if !errors.As(err, &fsmErr) || fsmErr.BadEvent() != "Kick" || fsmErr.CurrentState() != "Locked" {
+		t.Errorf("expected an Error for Kick in Locked, got %v", err)
+	}
+
+	err = fsm.Trigger("Locked", "Coin")
+	if errors.Is(err, ErrNoTransition) {
+		t.Errorf("action errors should not match ErrNoTransition: %v", err)
+	}
+	var ae *actionError
+	if !errors.As(err, &ae) || ae.action != "check" {
+		t.Errorf("expected the action error, got %v", err)
+	}
+}
+
+// paramsProcessor records the params of actions.
+type paramsProcessor struct {
+	recordingProcessor
+	params map[string]map[string]interface{}
+}
+
+func (p *paramsProcessor) ActionContext(c *EventContext) error {
+	p.params[c.Action] = c.Params
+	if c.Params != nil {
+		c.Params["sent"] = true
+	}
+	return nil
+}
+
+func TestRegisterActionParams(t *testing.T) {
+	p := &paramsProcessor{params: make(map[string]map[string]interface{})}
+	fsm := NewStateMachine(&DefaultDelegate{P: p},
+		Transition{From: "New", Event: "Register", To: "Registered", Action: "send-email"},
+		Transition{From: "Registered", Event: "Activate", To: "Active", Action: "activate"},
+	)
+	fsm.RegisterActionParams("send-email", map[string]interface{}{"template": "welcome"})
+
+	if err := fsm.Trigger("New", "Register"); err != nil {
+		t.Fatalf("trigger err: %v", err)
+	}
+	if err := fsm.Trigger("Registered", "Activate"); err != nil {
+		t.Fatalf("trigger err: %v", err)
+	}
+
+	if p.params["send-email"]["template"] != "welcome" {
+		t.Errorf("expected the registered params, got %v", p.params["send-email"])
+	}
+	if p.params["activate"] != nil {
+		t.Errorf("expected no params, got %v", p.params["activate"])
+	}
+
+	if err := fsm.Trigger("New", "Register"); err != nil {
+		t.Fatalf("trigger err: %v", err)
+	}
+	if _, ok := fsm.actionParams["send-email"]["sent"]; ok {
+		t.Errorf("expected actions not to change the registered params, got %v", fsm.actionParams["send-email"])
+	}
+}
+
+func TestActionRedirectPairing(t *testing.T) {
+	tests := []struct {
+		from, to, redirect string
+		want               string
+	}{
+		{"S", "S", "T", "[action:go exit:S enter:T]"},
+		{"S", "T", "S", "[exit:S action:go enter:S]"},
+		{"S", "T", "", "[exit:S action:go enter:T]"},
+		{"S", "S", "", "[action:go]"},
+	}
+
+	for _, tt := range tests {
+		p := &recordingProcessor{redirects: map[string]string{"go": tt.redirect}}
+		fsm := NewStateMachine(&DefaultDelegate{P: p}, Transition{From: tt.from, Event: "Go", To: tt.to, Action: "go"})
+		var entered string
+		fsm.With(WithFirstSeen(func(state string) { entered = state }))
+
+		if err := fsm.Trigger(tt.from, "Go"); err != nil {
+			t.Fatalf("trigger err: %v", err)
+		}
+		if got := fmt.Sprint(p.calls); got != tt.want {
+			t.Errorf("%s->%s redirected to %q: expected %s, got %s", tt.from, tt.to, tt.redirect, tt.want, got)
+		}
+		if want := tt.redirect; (want == "" && entered != tt.to) || (want != "" && entered != want) {
+			t.Errorf("%s->%s redirected to %q: unexpected new state %q", tt.from, tt.to, tt.redirect, entered)
+		}
+	}
+}
+
+// slowProcessor advances the clock in its actions.
+type slowProcessor struct {
+	recordingProcessor
+	clock *fakeClock
+}
+
+func (p *slowProcessor) Action(action string, fromState string, toState string, args []interface{}) error {
+	p.clock.Advance(250 * time.Millisecond)
+	return nil
+}
+
+func (p *slowProcessor) ActionContext(c *EventContext) error {
+	return p.Action(c.Action, c.From, c.To, c.Args)
+}
+
+func TestWithActionTimer(t *testing.T) {
+	clock := newFakeClock()
+	durations := make(map[string]time.Duration)
+	fsm := NewStateMachine(&DefaultDelegate{P: &slowProcessor{clock: clock}},
+		Transition{From: "Locked", Event: "Coin", To: "Unlocked", Action: "check"},
+	).With(WithClock(clock), WithActionTimer(func(action string, d time.Duration) {
+		durations[action] = d
+	}))
+
+	if err := fsm.Trigger("Locked", "Coin"); err != nil {
+		t.Fatalf("trigger err: %v", err)
+	}
+	if durations["check"] != 250*time.Millisecond {
+		t.Errorf("expected check to take 250ms, got %v", durations)
+	}
+}
+
+func TestStateEntryExit(t *testing.T) {
+	p := &recordingProcessor{}
+	fsm := NewStateMachine(&DefaultDelegate{P: p},
+		Transition{From: "Locked", Event: "Coin", To: "Unlocked", Action: "check"},
+		Transition{From: "Unlocked", Event: "Coin", To: "Unlocked", Action: "refund"},
+		Transition{From: "Unlocked", Event: "Push", To: "Locked"},
+	)
+	fsm.StateExit("Locked", "release")
+	fsm.StateEntry("Unlocked", "light-on")
+	fsm.StateExit("Unlocked", "light-off")
+
+	steps := [][2]string{{"Locked", "Coin"}, {"Unlocked", "Coin"}, {"Unlocked", "Push"}}
+	for _, step := range steps {
+		if err := fsm.Trigger(step[0], step[1]); err != nil {
+			t.Fatalf("trigger %s err: %v", step[1], err)
+		}
+	}
+
+	want := "[exit:Locked action:release action:check action:light-on enter:Unlocked " +
+		"action:refund " +
+		"exit:Unlocked action:light-off enter:Locked]"
+	if got := fmt.Sprint(p.calls); got != want {
+		t.Errorf("expected calls %s, got %s", want, got)
+	}
+}
+
+// argsProcessor appends the action to the args and records the args passed to OnEnter.
+type argsProcessor struct {
+	entered [][]interface{}
+}
+
+func (p *argsProcessor) OnExit(fromState string, args []interface{}) {}
+
+func (p *argsProcessor) Action(action string, fromState string, toState string, args []interface{}) error {
+	return errors.New("Action should not be called")
+}
+
+func (p *argsProcessor) ActionArgs(action string, fromState string, toState string, args []interface{}) ([]interface{}, error) {
+	return append(args[:len(args):len(args)], action), nil
+}
+
+func (p *argsProcessor) OnActionFailure(action string, fromState string, toState string, args []interface{}, err error) {
+}
+
+func (p *argsProcessor) OnEnter(toState string, args []interface{}) {
+	p.entered = append(p.entered, args)
+}
+
+func TestArgsProcessor(t *testing.T) {
+	p := &argsProcessor{}
+	fsm := NewStateMachine(&DefaultDelegate{P: p},
+		Transition{From: "New", Event: "Reserve", To: "Reserved", Action: "reserve"},
+		Transition{From: "Reserved", Event: "Charge", To: "Paid", Action: "charge"},
+	)
+
+	if err := fsm.Trigger("New", "Reserve", "order-1"); err != nil {
+		t.Fatalf("trigger err: %v", err)
+	}
+	if got := fmt.Sprint(p.entered); got != "[[order-1 reserve]]" {
+		t.Errorf("expected OnEnter to see the updated args, got %s", got)
+	}
+}
+
+func TestArgsProcessorFollowUp(t *testing.T) {
+	p := &recordingProcessor{emits: map[string][]string{"reserve": {"Charge"}}}
+	var charged []interface{}
+	fsm := NewStateMachine(&DefaultDelegate{P: &argsContextProcessor{p, &charged}},
+		Transition{From: "New", Event: "Reserve", To: "Reserved", Action: "reserve"},
+		Transition{From: "Reserved", Event: "Charge", To: "Paid", Action: "charge"},
+	)
+
+	if err := fsm.Trigger("New", "Reserve", "order-1"); err != nil {
+		t.Fatalf("trigger err: %v", err)
+	}
+	if got := fmt.Sprint(charged); got != "[order-1 reservation-1]" {
+		t.Errorf("expected the follow-up event to see the updated args, got %s", got)
+	}
+}
+
+// argsContextProcessor replaces the args in the reserve action and records the args of the charge action.
+type argsContextProcessor struct {
+	*recordingProcessor
+	charged *[]interface{}
+}
+
+func (p *argsContextProcessor) ActionContext(c *EventContext) error {
+	switch c.Action {
+	case "reserve":
+		c.Args = append(c.Args[:len(c.Args):len(c.Args)], "reservation-1")
+	case "charge":
+		*p.charged = c.Args
+	}
+	return p.recordingProcessor.ActionContext(c)
+}
+
+func TestTransitionFroms(t *testing.T) {
+	p := &recordingProcessor{}
+	fsm := NewStateMachine(&DefaultDelegate{P: p},
+		Transition{From: "New", Event: "Start", To: "Running", Action: "start"},
+		Transition{Froms: []string{"Pending", "Running"}, Event: "Cancel", To: "Cancelled", Action: "cancel"},
+	)
+
+	for _, from := range []string{"Pending", "Running"} {
+		if err := fsm.Trigger(from, "Cancel"); err != nil {
+			t.Errorf("trigger Cancel from %s err: %v", from, err)
+		}
+	}
+	if err := fsm.Trigger("New", "Cancel"); !errors.Is(err, ErrNoTransition) {
+		t.Errorf("expected no transition for Cancel from New, got %v", err)
+	}
+	if len(fsm.transitions) != 3 {
+		t.Errorf("expected the transitions to be expanded, got %v", fsm.transitions)
+	}
+}
+
+func TestWithActiveTags(t *testing.T) {
+	fsm := NewStateMachine(&DefaultDelegate{P: &recordingProcessor{}},
+		Transition{From: "Cart", Event: "Pay", To: "Paid", Action: "charge", Tags: []string{"billing"}},
+		Transition{From: "Paid", Event: "Ship", To: "Shipped", Action: "ship", Tags: []string{"shipping"}},
+		Transition{From: "Cart", Event: "Cancel", To: "Cancelled", Action: "cancel"},
+	)
+
+	tests := []struct {
+		tags []string
+		want map[string]bool
+	}{
+		{nil, map[string]bool{"Pay": true, "Ship": true, "Cancel": true}},
+		{[]string{"billing"}, map[string]bool{"Pay": true, "Ship": false, "Cancel": true}},
+		{[]string{"shipping"}, map[string]bool{"Pay": false, "Ship": true, "Cancel": true}},
+	}
+	for _, tt := range tests {
+		fsm.With(WithActiveTags(tt.tags...))
+		for event, enabled := range tt.want {
+			from := "Cart"
+			if event == "Ship" {
+				from = "Paid"
+			}
+			if err := fsm.Trigger(from, event); (err == nil) != enabled {
+				t.Errorf("tags %v: expected %s enabled=%v, got %v", tt.tags, event, enabled, err)
+			}
+		}
+	}
+}
+
+func TestWithEventNormalizer(t *testing.T) {
+	p := &recordingProcessor{}
+	fsm := NewStateMachine(&DefaultDelegate{P: p},
+		Transition{From: "Locked", Event: "Coin", To: "Unlocked", Action: "check"},
+		Transition{From: "Unlocked", Event: "push", To: "Locked", Action: "pass"},
+	).With(WithEventNormalizer(func(event string) string {
+		return strings.ToLower(strings.TrimSpace(event))
+	}))
+
+	if err := fsm.Trigger("Locked", " COIN "); err != nil {
+		t.Fatalf("trigger err: %v", err)
+	}
+	if err := fsm.Trigger("Unlocked", "Push"); err != nil {
+		t.Fatalf("trigger err: %v", err)
+	}
+	if got := fmt.Sprint(p.events); got != "[ COIN  Push]" {
+		t.Errorf("expected the delegate to receive the original events, got %q", got)
+	}
+}
+
+func TestWithEventNormalizerSharedTransitions(t *testing.T) {
+	ts := []Transition{{From: "A", Event: "Go", To: "B"}}
+	a := NewStateMachine(nil, ts...)
+	b := NewStateMachine(nil, ts...).With(WithEventNormalizer(strings.ToLower))
+
+	if ts[0].Event != "Go" {
+		t.Errorf("expected the transitions of the caller to be kept, got %s", ts[0].Event)
+	}
+	if err := a.Trigger("A", "Go"); err != nil {
+		t.Errorf("expected the other machine to be unaffected, got %v", err)
+	}
+	if err := b.Trigger("A", "GO"); err != nil {
+		t.Errorf("expected the normalized event to match, got %v", err)
+	}
+}
+
+// vetoingProcessor vetoes entering the states in full.
+type vetoingProcessor struct {
+	recordingProcessor
+	full map[string]bool
+}
+
+func (p *vetoingProcessor) CanEnter(toState string, args []interface{}) error {
+	if p.full[toState] {
+		return errors.New("state is full")
+	}
+	return nil
+}
+
+func TestCanEnterVeto(t *testing.T) {
+	p := &vetoingProcessor{full: map[string]bool{"Unlocked": true}}
+	fsm := NewStateMachine(&DefaultDelegate{P: p},
+		Transition{From: "Locked", Event: "Coin", To: "Unlocked", Action: "check"},
+		Transition{From: "Locked", Event: "Push", To: "Broken", Action: "break"},
+	)
+
+	err := fsm.Trigger("Locked", "Coin")
+	var vetoed *EntryVetoedError
+	if !errors.As(err, &vetoed) || vetoed.State != "Unlocked" {
+		t.Fatalf("expected entry vetoed error, got %v", err)
+	}
+	want := "[exit:Locked action:check failure:check]"
+	if got := fmt.Sprint(p.calls); got != want {
+		t.Errorf("expected calls %s, got %s", want, got)
+	}
+
+	p.calls = nil
+	if err := fsm.Trigger("Locked", "Push"); err != nil {
+		t.Fatalf("trigger err: %v", err)
+	}
+	want = "[exit:Locked action:break enter:Broken]"
+	if got := fmt.Sprint(p.calls); got != want {
+		t.Errorf("expected calls %s, got %s", want, got)
+	}
+}
+
+// argsCapturingProcessor records the args of each action.
+type argsCapturingProcessor struct {
+	recordingProcessor
+	args [][]interface{}
+}
+
+func (p *argsCapturingProcessor) ActionContext(c *EventContext) error {
+	p.args = append(p.args, c.Args)
+	return nil
+}
+
+func TestWithArgsTransformer(t *testing.T) {
+	p := &argsCapturingProcessor{}
+	fsm := NewStateMachine(&DefaultDelegate{P: p},
+		Transition{From: "Locked", Event: "Coin", To: "Unlocked", Action: "check"},
+	).With(WithArgsTransformer(func(from, event, to string, args []interface{}) []interface{} {
+		return append(args, from+"/"+event+"/"+to)
+	}))
+
+	if err := fsm.Trigger("Locked", "Coin", 1); err != nil {
+		t.Fatalf("trigger err: %v", err)
+	}
+	want := "[[1 Locked/Coin/Unlocked]]"
+	if got := fmt.Sprint(p.args); got != want {
+		t.Errorf("expected args %s, got %s", want, got)
+	}
+}
+
+func TestPauseResume(t *testing.T) {
+	p := &recordingProcessor{}
+	fsm := NewStateMachine(&DefaultDelegate{P: p},
+		Transition{From: "Locked", Event: "Coin", To: "Unlocked", Action: "check"},
+	)
+
+	fsm.Pause()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := fsm.Trigger("Locked", "Coin"); !errors.Is(err, ErrPaused) {
+				t.Errorf("expected ErrPaused, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if len(p.calls) != 0 {
+		t.Fatalf("expected no calls while paused, got %v", p.calls)
+	}
+
+	fsm.Resume()
+	if err := fsm.Trigger("Locked", "Coin"); err != nil {
+		t.Fatalf("trigger err: %v", err)
+	}
+	if fsm.Paused() {
+		t.Error("expected the machine to be resumed")
+	}
+}
+
+func TestWithContinueOnActionError(t *testing.T) {
+	newFSM := func(p EventProcessor, continueOnError bool) *StateMachine {
+		fsm := NewStateMachine(&DefaultDelegate{P: p},
+			Transition{From: "Locked", Event: "Coin", To: "Unlocked", Action: "notify-sms"},
+		).With(WithContinueOnActionError(continueOnError))
+		fsm.StateExit("Locked", "notify-email")
+		fsm.StateEntry("Unlocked", "log")
+		return fsm
+	}
+	fail := map[string]bool{"notify-email": true, "notify-sms": true}
+
+	p := &compensationProcessor{fail: fail}
+	err := newFSM(p, true).Trigger("Locked", "Coin")
+	var errs ActionErrors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("expected two action errors, got %v", err)
+	}
+	if err.Error() != "failed notify-email\nfailed notify-sms" {
+		t.Errorf("unexpected error message %q", err.Error())
+	}
+	want := "[exit:Locked action:notify-email failure:notify-email action:notify-sms failure:notify-sms action:log]"
+	if got := fmt.Sprint(p.calls); got != want {
+		t.Errorf("expected calls %s, got %s", want, got)
+	}
+
+	p = &compensationProcessor{fail: fail}
+	err = newFSM(p, false).Trigger("Locked", "Coin")
+	if err == nil || err.Error() != "failed notify-email" {
+		t.Fatalf("expected the first error, got %v", err)
+	}
+	want = "[exit:Locked action:notify-email failure:notify-email]"
+	if got := fmt.Sprint(p.calls); got != want {
+		t.Errorf("expected calls %s, got %s", want, got)
+	}
+}
+
+func TestWithCaseInsensitive(t *testing.T) {
+	p := &recordingProcessor{}
+	fsm := NewStateMachine(&DefaultDelegate{P: p},
+		Transition{From: "Locked", Event: "Coin", To: "Unlocked", Action: "check"},
+	)
+
+	if err := fsm.Trigger("locked", "coin"); !errors.Is(err, ErrNoTransition) {
+		t.Fatalf("expected ErrNoTransition, got %v", err)
+	}
+
+	fsm.With(WithCaseInsensitive(true))
+	if err := fsm.Trigger("locked", "coin"); err != nil {
+		t.Fatalf("trigger err: %v", err)
+	}
+	want := "[exit:locked action:check enter:Unlocked]"
+	if got := fmt.Sprint(p.calls); got != want {
+		t.Errorf("expected calls %s, got %s", want, got)
+	}
+	if trans, ok := fsm.FindTransition("LOCKED", "COIN"); !ok || trans.From != "Locked" || trans.Event != "Coin" {
+		t.Errorf("expected the original transition, got %v", trans)
+	}
+	if events := fmt.Sprint(fsm.AvailableEvents("locked")); events != "[Coin]" {
+		t.Errorf("unexpected available events %s", events)
+	}
+}
+
+func TestWithIgnoredEvents(t *testing.T) {
+	p := &recordingProcessor{}
+	fsm := NewStateMachine(&DefaultDelegate{P: p},
+		Transition{From: "Idle", Event: "Start", To: "Running", Action: "start"},
+		Transition{From: "Running", Event: "Stop", To: "Idle", Action: "stop"},
+	).With(WithIgnoredEvents("Idle", "Stop", "Heartbeat"))
+
+	if err := fsm.Trigger("Idle", "Stop"); err != nil {
+		t.Fatalf("expected the ignored event to be a no-op, got %v", err)
+	}
+	if len(p.calls) != 0 {
+		t.Errorf("expected no delegate calls, got %v", p.calls)
+	}
+	if err := fsm.Trigger("Running", "Heartbeat"); !errors.Is(err, ErrNoTransition) {
+		t.Errorf("expected ErrNoTransition in a state that doesn't ignore the event, got %v", err)
+	}
+	if err := fsm.Trigger("Idle", "Start"); err != nil || len(p.calls) == 0 {
+		t.Errorf("expected the transition to run, got %v %v", err, p.calls)
+	}
+}
+
+func TestWithIgnoredEventsNormalized(t *testing.T) {
+	fsm := NewStateMachine(nil,
+		Transition{From: "Idle", Event: "start", To: "Running"},
+	).With(WithEventNormalizer(strings.ToLower), WithIgnoredEvents("Idle", "ping"))
+	if err := fsm.Trigger("Idle", "PING"); err != nil {
+		t.Errorf("expected the normalized event to be ignored, got %v", err)
+	}
+
+	fsm.With(WithCaseInsensitive(true), WithIgnoredEvents("Running", "Heartbeat"))
+	if err := fsm.Trigger("RUNNING", "heartbeat"); err != nil {
+		t.Errorf("expected the event to be ignored case-insensitively, got %v", err)
+	}
+}
+
+// fallbackProcessor handles the check action and the other actions generically.
+type fallbackProcessor struct {
+	TurnstileEventProcessor
+	fallbacks []string
+}
+
+func (p *fallbackProcessor) Action(action string, fromState string, toState string, args []interface{}) error {
+	if action == "check" {
+		return nil
+	}
+	return fmt.Errorf("no handler: %w", ErrActionNotFound)
+}
+
+func (p *fallbackProcessor) FallbackAction(action string, fromState string, toState string, args []interface{}) error {
+	p.fallbacks = append(p.fallbacks, action)
+	return nil
+}
+
+func TestFallbackAction(t *testing.T) {
+	p := &fallbackProcessor{}
+	fsm := NewStateMachine(&DefaultDelegate{P: p}, initFSM().transitions...)
+
+	ts := &Turnstile{State: "Locked"}
+	for _, event := range []string{"Coin", "Push"} {
+		if err := fsm.Trigger(ts.State, event, ts); err != nil {
+			t.Fatalf("trigger %s err: %v", event, err)
+		}
+	}
+	if got := fmt.Sprint(p.fallbacks); got != "[pass]" {
+		t.Errorf("expected the fallback for pass, got %s", got)
+	}
+
+	rp, _ := NewReflectProcessor(&turnstileActions{})
+	if err := rp.Action("break", "Locked", "Broken", nil); !errors.Is(err, ErrActionNotFound) {
+		t.Errorf("expected ErrActionNotFound for a missing method, got %v", err)
+	}
+}
+
+func TestNilDelegate(t *testing.T) {
+	fsm := NewStateMachine(nil,
+		Transition{From: "Locked", Event: "Coin", To: "Unlocked", Action: "check"},
+		Transition{From: "Unlocked", Event: "Push", To: "Locked"},
+	)
+
+	if err := fsm.Trigger("Locked", "Coin"); !errors.Is(err, ErrNoDelegate) {
+		t.Errorf("expected ErrNoDelegate, got %v", err)
+	}
+	if err := fsm.Trigger("Unlocked", "Push"); err != nil {
+		t.Errorf("expected a transition without action to succeed, got %v", err)
+	}
+}
+
+func TestHitCounts(t *testing.T) {
+	fsm := NewStateMachine(nil,
+		Transition{From: "Locked", Event: "Coin", To: "Unlocked"},
+		Transition{From: "Unlocked", Event: "Push", To: "Locked"},
+		Transition{From: Wildcard, Event: "Reset", To: "Locked"},
+	).With(WithStats(true))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fsm.Trigger("Locked", "Coin")
+			fsm.Trigger("Unlocked", "Reset")
+			fsm.Trigger("Unlocked", "Kick")
+		}()
+	}
+	wg.Wait()
+	fsm.Trigger("Unlocked", "Push")
+
+	want := map[string]uint64{"Locked/Coin": 10, "*/Reset": 10, "Unlocked/Push": 1}
+	if got := fsm.HitCounts(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestTriggerMatched(t *testing.T) {
+	fsm := NewStateMachine(nil,
+		Transition{From: "Idle", Event: "Start", To: "Running"},
+	).With(WithIgnoredEvents("Idle", "Heartbeat"))
+
+	if matched, err := fsm.TriggerMatched("Idle", "Start"); !matched || err != nil {
+		t.Errorf("expected the transition without action to match and succeed, got %v, %v", matched, err)
+	}
+	if matched, err := fsm.TriggerMatched("Idle", "Heartbeat"); matched || err != nil {
+		t.Errorf("expected the ignored event to succeed without a match, got %v, %v", matched, err)
+	}
+	if matched, err := fsm.TriggerMatched("Idle", "Stop"); matched || !errors.Is(err, ErrNoTransition) {
+		t.Errorf("expected no match and ErrNoTransition, got %v, %v", matched, err)
+	}
+	if err := fsm.Trigger("Idle", "Start"); err != nil {
+		t.Errorf("expected Trigger to succeed for the transition without action, got %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+// Package fsmexpr compiles the conditions of transitions, see fsm.Transition.Condition, into guards with
+// expr (https://github.com/expr-lang/expr), so guards can be configured in JSON without recompiling.
+// It is a separate package so the fsm package doesn't depend on expr.
+package fsmexpr
+
+import (
+	"fmt"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+
+	fsm "github.com/smallnest/gofsm"
+)
+
+// Compile returns copies of the transitions whose Condition is compiled into their Guard. It returns an error for
+// a condition that doesn't compile, or for a transition that has both a Condition and a Guard.
+//
+// A condition is evaluated with these variables: from, event, args, and the named values of fsm.ValuesOf(args),
+// e.g. `quantity > 0` for TriggerWithValues(state, event, fsm.Values{"quantity": 1}).
+// Names of expr builtins like count or len can't be used as variables. A condition whose evaluation fails,
+// e.g. because a variable is missing, rejects. The GuardName defaults to the condition.
+func Compile(transitions ...fsm.Transition) ([]fsm.Transition, error) {
+	compiled := make([]fsm.Transition, 0, len(transitions))
+	for _, t := range transitions {
+		if t.Condition != "" {
+			if t.Guard != nil || t.ContextGuard != nil {
+				return nil, fmt.Errorf("state machine error: transition %s has both a condition and a guard", t)
+			}
+			program, err := expr.Compile(t.Condition, expr.AllowUndefinedVariables())
+			if err != nil {
+				return nil, fmt.Errorf("state machine error: invalid condition of %s: %w", t, err)
+			}
+			t.Guard = guard(program)
+			if t.GuardName == "" {
+				t.GuardName = t.Condition
+			}
+		}
+		compiled = append(compiled, t)
+	}
+	return compiled, nil
+}
+
+// NewStateMachine compiles the conditions and creates a state machine, so invalid conditions are reported at construction.
+func NewStateMachine(delegate fsm.Delegate, transitions ...fsm.Transition) (*fsm.StateMachine, error) {
+	compiled, err := Compile(transitions...)
+	if err != nil {
+		return nil, err
+	}
+	return fsm.NewStateMachine(delegate, compiled...), nil
+}
+
+func guard(program *vm.Program) fsm.Guard {
+	return func(from string, event string, args []interface{}) bool {
+		env := map[string]interface{}{"from": from, "event": event, "args": args}
+		for k, v := range fsm.ValuesOf(args) {
+			env[k] = v
+		}
+		out, err := expr.Run(program, env)
+		pass, _ := out.(bool)
+		return err == nil && pass
+	}
+}
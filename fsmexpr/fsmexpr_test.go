@@ -0,0 +1,43 @@
+package fsmexpr
+
+import (
+	"errors"
+	"testing"
+
+	fsm "github.com/smallnest/gofsm"
+)
+
+func TestCompile(t *testing.T) {
+	m, err := NewStateMachine(nil,
+		fsm.Transition{From: "Cart", Event: "Checkout", To: "Paying", Condition: "quantity > 0"},
+		fsm.Transition{From: "Cart", Event: "Checkout", To: "Cart", Condition: `event == "Checkout" && quantity == 0`},
+	)
+	if err != nil {
+		t.Fatalf("compile err: %v", err)
+	}
+
+	tests := []struct {
+		values fsm.Values
+		want   string
+	}{
+		{fsm.Values{"quantity": 2}, "Paying"},
+		{fsm.Values{"quantity": 0}, "Cart"},
+	}
+	for _, tt := range tests {
+		trans, ok := m.FindTransition("Cart", "Checkout", tt.values)
+		if !ok || trans.To != tt.want {
+			t.Errorf("values %v: expected %s, got %v", tt.values, tt.want, trans)
+		}
+	}
+
+	if err := m.Trigger("Cart", "Checkout"); !errors.Is(err, fsm.ErrNoTransition) {
+		t.Errorf("expected a missing variable to reject, got %v", err)
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	_, err := NewStateMachine(nil, fsm.Transition{From: "Cart", Event: "Checkout", To: "Paying", Condition: "quantity >"})
+	if err == nil {
+		t.Error("expected an error for an invalid condition")
+	}
+}
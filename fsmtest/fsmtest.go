@@ -0,0 +1,47 @@
+// Package fsmtest provides helpers for testing state machines, so the fsm package doesn't import testing.
+package fsmtest
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	fsm "github.com/smallnest/gofsm"
+)
+
+// AssertEvents fails the test if the events available in the state, see StateMachine.AvailableEvents,
+// are not exactly the wanted events. The order of events doesn't matter.
+func AssertEvents(t testing.TB, m *fsm.StateMachine, state string, want ...string) {
+	t.Helper()
+
+	got := sorted(m.AvailableEvents(state))
+	want = sorted(want)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") || len(got) != len(want) {
+		t.Errorf("events of state %s: expected %v, got %v", state, want, got)
+	}
+}
+
+// DriveAll triggers each transition of the machine once from its From state with args and returns which transitions
+// were exercised, keyed by Transition.String. Use the delegate of the machine as a spy to check the side effects.
+// A transition is exercised if its event ran its action and reached its To state. The test fails for each transition
+// with an action that was not exercised, e.g. because a guard rejected or a more specific transition matched.
+func DriveAll(t testing.TB, m *fsm.StateMachine, args ...interface{}) map[string]bool {
+	t.Helper()
+
+	exercised := make(map[string]bool)
+	for _, tr := range m.Transitions() {
+		effects, err := m.TriggerRecorded(tr.From, tr.Event, args...)
+		ok := err == nil && len(effects) > 0 && effects[0].Action == tr.Action && effects[0].To == tr.To
+		exercised[tr.String()] = exercised[tr.String()] || ok
+		if !ok && tr.Action != "" {
+			t.Errorf("transition %s: action %s was not exercised: %v", tr, tr.Action, err)
+		}
+	}
+	return exercised
+}
+
+func sorted(events []string) []string {
+	s := append([]string(nil), events...)
+	sort.Strings(s)
+	return s
+}
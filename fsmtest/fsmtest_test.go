@@ -0,0 +1,72 @@
+package fsmtest
+
+import (
+	"testing"
+
+	fsm "github.com/smallnest/gofsm"
+)
+
+// recorder is a testing.TB that records failures instead of failing the test.
+type recorder struct {
+	testing.TB
+	failed bool
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failed = true
+}
+
+func TestAssertEvents(t *testing.T) {
+	m := fsm.NewStateMachine(nil,
+		fsm.Transition{From: "Locked", Event: "Coin", To: "Unlocked"},
+		fsm.Transition{From: "Locked", Event: "Push", To: "Locked"},
+		fsm.Transition{From: "Unlocked", Event: "Push", To: "Locked"},
+	)
+
+	AssertEvents(t, m, "Locked", "Push", "Coin")
+
+	r := &recorder{TB: t}
+	AssertEvents(r, m, "Unlocked", "Coin", "Push")
+	if !r.failed {
+		t.Error("expected AssertEvents to fail for a missing event")
+	}
+}
+
+// spy records the invoked actions.
+type spy struct {
+	actions []string
+}
+
+func (s *spy) HandleEvent(action string, fromState string, toState string, args []interface{}) error {
+	s.actions = append(s.actions, action)
+	return nil
+}
+
+func TestDriveAll(t *testing.T) {
+	d := &spy{}
+	m := fsm.NewStateMachine(d,
+		fsm.Transition{From: "Locked", Event: "Coin", To: "Unlocked", Action: "check"},
+		fsm.Transition{From: "Unlocked", Event: "Push", To: "Locked", Action: "pass"},
+		fsm.Transition{From: "Unlocked", Event: "Coin", To: "Unlocked"},
+	)
+
+	exercised := DriveAll(t, m)
+	if len(exercised) != 3 || !exercised["Locked --(Coin/check)--> Unlocked"] || !exercised["Unlocked --(Coin)--> Unlocked"] {
+		t.Errorf("expected all transitions to be exercised, got %v", exercised)
+	}
+	if len(d.actions) != 2 {
+		t.Errorf("expected the actions to run once, got %v", d.actions)
+	}
+
+	m = fsm.NewStateMachine(d,
+		fsm.Transition{From: "Locked", Event: "Coin", To: "Unlocked", Action: "check"},
+		fsm.Transition{From: "Locked", Event: "Coin", To: "Broken", Action: "break"},
+	)
+	r := &recorder{TB: t}
+	exercised = DriveAll(r, m)
+	if !r.failed || exercised["Locked --(Coin/break)--> Broken"] {
+		t.Errorf("expected the shadowed transition not to be exercised, got %v", exercised)
+	}
+}
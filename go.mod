@@ -1,3 +1,5 @@
 module github.com/smallnest/gofsm
 
-go 1.12
+go 1.18
+
+require github.com/expr-lang/expr v1.16.9
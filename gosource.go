@@ -0,0 +1,65 @@
+package fsm
+
+import (
+	"fmt"
+	"go/format"
+	"io"
+	"strings"
+)
+
+// ExportGoSource writes the transitions as a gofmt-ed Go declaration of a []fsm.Transition named varName, e.g. to
+// freeze a machine built dynamically into code. Only the fields that are set are written. Guard functions can't be
+// written, the guard names are kept. Timeouts are written in nanoseconds.
+func (m *StateMachine) ExportGoSource(w io.Writer, varName string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "var %s = []fsm.Transition{\n", varName)
+	for _, t := range m.transitions {
+		b.WriteString("{" + strings.Join(goFields(t), ", ") + "},\n")
+	}
+	b.WriteString("}\n")
+
+	src, err := format.Source([]byte(b.String()))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// goFields returns the key-value pairs of the fields of the transition that are set.
+func goFields(t Transition) []string {
+	fields := []string{fmt.Sprintf("From: %q", t.From), fmt.Sprintf("Event: %q", t.Event), fmt.Sprintf("To: %q", t.To)}
+	for _, f := range []struct{ name, value string }{
+		{"Action", t.Action}, {"GuardName", t.GuardName}, {"Condition", t.Condition},
+		{"OnGuardFail", t.OnGuardFail}, {"OnFailure", t.OnFailure},
+	} {
+		if f.value != "" {
+			fields = append(fields, fmt.Sprintf("%s: %q", f.name, f.value))
+		}
+	}
+	if len(t.Froms) > 0 {
+		fields = append(fields, "Froms: "+goStrings(t.Froms))
+	}
+	if t.Timeout != 0 {
+		fields = append(fields, fmt.Sprintf("Timeout: %d", int64(t.Timeout)))
+	}
+	if len(t.Tags) > 0 {
+		fields = append(fields, "Tags: "+goStrings(t.Tags))
+	}
+	if t.Weight != 0 {
+		fields = append(fields, fmt.Sprintf("Weight: %d", t.Weight))
+	}
+	if t.Deprecated {
+		fields = append(fields, "Deprecated: true")
+	}
+	return fields
+}
+
+// goStrings returns a []string literal.
+func goStrings(s []string) string {
+	quoted := make([]string, len(s))
+	for i, v := range s {
+		quoted[i] = fmt.Sprintf("%q", v)
+	}
+	return "[]string{" + strings.Join(quoted, ", ") + "}"
+}
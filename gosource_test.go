@@ -0,0 +1,66 @@
+package fsm
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportGoSource(t *testing.T) {
+	fsm := NewStateMachine(nil,
+		Transition{From: "Locked", Event: "Coin", To: "Unlocked", Action: "check", Weight: 7},
+		Transition{From: "Locked", Event: "Push", To: "Locked", GuardName: "staffed", Condition: `staff > 0 && name != "x"`},
+		Transition{From: "Unlocked", Event: "Timeout", To: "Locked", Action: "lock", Timeout: 30 * time.Second, OnFailure: "Broken"},
+		Transition{From: "Unlocked", Event: "Coin", To: "Unlocked", Tags: []string{"billing"}, Deprecated: true},
+	)
+
+	var b strings.Builder
+	if err := fsm.ExportGoSource(&b, "turnstile"); err != nil {
+		t.Fatalf("export err: %v", err)
+	}
+
+	file, err := parser.ParseFile(token.NewFileSet(), "", "package p\n\n"+b.String(), 0)
+	if err != nil {
+		t.Fatalf("parse err: %v\n%s", err, b.String())
+	}
+	spec := file.Decls[0].(*ast.GenDecl).Specs[0].(*ast.ValueSpec)
+	if spec.Names[0].Name != "turnstile" {
+		t.Errorf("unexpected variable %s", spec.Names[0].Name)
+	}
+
+	var transitions []Transition
+	for _, elt := range spec.Values[0].(*ast.CompositeLit).Elts {
+		var tr Transition
+		v := reflect.ValueOf(&tr).Elem()
+		for _, kv := range elt.(*ast.CompositeLit).Elts {
+			kv := kv.(*ast.KeyValueExpr)
+			field := v.FieldByName(kv.Key.(*ast.Ident).Name)
+			switch value := kv.Value.(type) {
+			case *ast.BasicLit:
+				if value.Kind == token.INT {
+					n, _ := strconv.ParseInt(value.Value, 10, 64)
+					field.SetInt(n)
+				} else {
+					s, _ := strconv.Unquote(value.Value)
+					field.SetString(s)
+				}
+			case *ast.Ident:
+				field.SetBool(value.Name == "true")
+			case *ast.CompositeLit:
+				for _, e := range value.Elts {
+					s, _ := strconv.Unquote(e.(*ast.BasicLit).Value)
+					field.Set(reflect.Append(field, reflect.ValueOf(s)))
+				}
+			}
+		}
+		transitions = append(transitions, tr)
+	}
+	if !reflect.DeepEqual(transitions, fsm.transitions) {
+		t.Errorf("expected %v, got %v", fsm.transitions, transitions)
+	}
+}
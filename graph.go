@@ -0,0 +1,221 @@
+package fsm
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// states returns the distinct states of the transitions in definition order, without Wildcard.
+func (m *StateMachine) states() []string {
+	var states []string
+	seen := map[string]bool{Wildcard: true}
+	for _, t := range m.transitions {
+		for _, s := range []string{t.From, t.To} {
+			if !seen[s] {
+				seen[s] = true
+				states = append(states, s)
+			}
+		}
+	}
+	return states
+}
+
+// successors returns the target states of each state, a transition from Wildcard leaves every state.
+func (m *StateMachine) successors(states []string) map[string][]string {
+	next := make(map[string][]string)
+	for _, t := range m.transitions {
+		if t.From != Wildcard {
+			next[t.From] = append(next[t.From], t.To)
+			continue
+		}
+		for _, s := range states {
+			next[s] = append(next[s], t.To)
+		}
+	}
+	return next
+}
+
+// Cycles returns the cycles of the transition graph, e.g. to assert that a workflow is acyclic. Each cycle is a
+// strongly connected component: states that can all reach each other, in definition order. A state with
+// a self-transition is a cycle by itself. Guards are not evaluated.
+func (m *StateMachine) Cycles() [][]string {
+	states := m.states()
+	next := m.successors(states)
+	order := make(map[string]int, len(states))
+	for i, s := range states {
+		order[s] = i
+	}
+
+	// Tarjan's strongly connected components algorithm.
+	index := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var components [][]string
+
+	var connect func(s string)
+	connect = func(s string) {
+		index[s] = len(index)
+		low[s] = index[s]
+		stack = append(stack, s)
+		onStack[s] = true
+
+		for _, to := range next[s] {
+			if _, visited := index[to]; !visited {
+				connect(to)
+				if low[to] < low[s] {
+					low[s] = low[to]
+				}
+			} else if onStack[to] && index[to] < low[s] {
+				low[s] = index[to]
+			}
+		}
+
+		if low[s] != index[s] {
+			return
+		}
+		var component []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == s {
+				break
+			}
+		}
+		components = append(components, component)
+	}
+
+	for _, s := range states {
+		if _, visited := index[s]; !visited {
+			connect(s)
+		}
+	}
+
+	var cycles [][]string
+	for _, c := range components {
+		if len(c) == 1 && !contains(next[c[0]], c[0]) {
+			continue
+		}
+		sort.Slice(c, func(i, j int) bool { return order[c[i]] < order[c[j]] })
+		cycles = append(cycles, c)
+	}
+	sort.Slice(cycles, func(i, j int) bool { return order[cycles[i][0]] < order[cycles[j][0]] })
+	return cycles
+}
+
+func contains(states []string, state string) bool {
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// Degree returns the number of transitions into and out of the state, self-transitions count for both.
+// Transitions from Wildcard count as transitions out of every state.
+func (m *StateMachine) Degree(state string) (in, out int) {
+	for _, t := range m.transitions {
+		if t.To == state {
+			in++
+		}
+		if t.From == state || t.From == Wildcard {
+			out++
+		}
+	}
+	return in, out
+}
+
+// SuggestInitial returns the states without transitions into them in definition order, they are candidate initial
+// states when the initial state is not known.
+func (m *StateMachine) SuggestInitial() []string {
+	var states []string
+	for _, s := range m.states() {
+		if in, _ := m.Degree(s); in == 0 {
+			states = append(states, s)
+		}
+	}
+	return states
+}
+
+// SuggestFinals returns the states without transitions out of them in definition order, they are candidate final states.
+func (m *StateMachine) SuggestFinals() []string {
+	var states []string
+	for _, s := range m.states() {
+		if _, out := m.Degree(s); out == 0 {
+			states = append(states, s)
+		}
+	}
+	return states
+}
+
+// SelfLoops returns the transitions that don't change state in definition order, see Transition.ChangesState.
+func (m *StateMachine) SelfLoops() []Transition {
+	var loops []Transition
+	for _, t := range m.transitions {
+		if !t.ChangesState() {
+			loops = append(loops, t)
+		}
+	}
+	return loops
+}
+
+// reachable returns the states reachable from the initial state with a breadth-first search, including the initial
+// state if it is a state of the transitions. Guards are not evaluated.
+func (m *StateMachine) reachable(initial string) map[string]bool {
+	states := m.states()
+	seen := make(map[string]bool)
+	if !contains(states, initial) {
+		return seen
+	}
+	next := m.successors(states)
+	seen[initial] = true
+	queue := []string{initial}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		for _, to := range next[s] {
+			if !seen[to] {
+				seen[to] = true
+				queue = append(queue, to)
+			}
+		}
+	}
+	return seen
+}
+
+// ReachableCount returns the number of distinct states reachable from the initial state, including it, e.g. for
+// complexity metrics. It is 0 if the initial state is not a state of the transitions.
+func (m *StateMachine) ReachableCount(initial string) int {
+	return len(m.reachable(initial))
+}
+
+// RandomWalk picks up to n random events from the start state, each one of the events matching a transition from the
+// current state, e.g. to generate valid event sequences for property tests of a delegate. It stops early at a state
+// without such events and returns the events with the state they lead to. Guards are evaluated without args and no
+// actions are run. The walk is deterministic for a seeded rng, a nil rng uses the global source of math/rand.
+func (m *StateMachine) RandomWalk(start string, n int, rng *rand.Rand) (events []string, finalState string) {
+	state := start
+	for len(events) < n {
+		var valid, targets []string
+		for _, event := range m.AvailableEvents(state) {
+			if t, ok := m.FindTransition(state, event); ok {
+				valid, targets = append(valid, event), append(targets, t.To)
+			}
+		}
+		if len(valid) == 0 {
+			break
+		}
+		var i int
+		if rng == nil {
+			i = rand.Intn(len(valid))
+		} else {
+			i = rng.Intn(len(valid))
+		}
+		events = append(events, valid[i])
+		state = targets[i]
+	}
+	return events, state
+}
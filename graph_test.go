@@ -0,0 +1,141 @@
+package fsm
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestCycles(t *testing.T) {
+	if got := fmt.Sprint(initFSM().Cycles()); got != "[[Locked Unlocked]]" {
+		t.Errorf("expected the turnstile cycle, got %s", got)
+	}
+
+	fsm := NewStateMachine(nil,
+		Transition{From: "Draft", Event: "Submit", To: "Review"},
+		Transition{From: "Review", Event: "Approve", To: "Published"},
+		Transition{From: "Published", Event: "Touch", To: "Published"},
+	)
+	if got := fmt.Sprint(fsm.Cycles()); got != "[[Published]]" {
+		t.Errorf("expected the self-transition cycle, got %s", got)
+	}
+
+	acyclic := NewStateMachine(nil,
+		Transition{From: "Draft", Event: "Submit", To: "Review"},
+		Transition{From: "Review", Event: "Approve", To: "Published"},
+	)
+	if cycles := acyclic.Cycles(); len(cycles) != 0 {
+		t.Errorf("expected no cycles, got %v", cycles)
+	}
+}
+
+func TestDegree(t *testing.T) {
+	if in, out := initFSM().Degree("Locked"); in != 2 || out != 2 {
+		t.Errorf("expected 2 transitions into and out of Locked, got %d, %d", in, out)
+	}
+
+	fsm := NewStateMachine(nil,
+		Transition{From: "Draft", Event: "Submit", To: "Review"},
+		Transition{From: "Review", Event: "Reject", To: "Draft"},
+		Transition{From: "Review", Event: "Approve", To: "Published"},
+		Transition{From: "Import", Event: "Submit", To: "Review"},
+		Transition{From: "Review", Event: "Drop", To: "Deleted"},
+	)
+	if got := fmt.Sprint(fsm.SuggestInitial()); got != "[Import]" {
+		t.Errorf("expected the initial state Import, got %s", got)
+	}
+	if got := fmt.Sprint(fsm.SuggestFinals()); got != "[Published Deleted]" {
+		t.Errorf("expected the final states Published and Deleted, got %s", got)
+	}
+}
+
+func TestSelfLoops(t *testing.T) {
+	loops := initFSM().SelfLoops()
+	if got := fmt.Sprint(loops); got != "[Locked --(Push/invalid-push)--> Locked Unlocked --(Coin/repeat-check)--> Unlocked]" {
+		t.Errorf("expected the turnstile self-loops, got %s", got)
+	}
+	if (Transition{From: "Locked", Event: "Coin", To: "Unlocked"}).ChangesState() != true {
+		t.Error("expected Locked -> Unlocked to change state")
+	}
+}
+
+func TestChangesStateWildcard(t *testing.T) {
+	reset := Transition{From: Wildcard, Event: "Reset", To: "Locked", Action: "reset"}
+	if !reset.ChangesState() {
+		t.Error("expected a transition from Wildcard to change state")
+	}
+
+	p := &recordingProcessor{}
+	var changes []bool
+	fsm := NewStateMachine(delegateFunc(func(action string, fromState string, toState string, args []interface{}) error {
+		c := &EventContext{Action: action, From: fromState, To: toState}
+		changes = append(changes, c.ChangesState())
+		return (&DefaultDelegate{P: p}).HandleEventContext(c)
+	}), reset)
+	for _, state := range []string{"Locked", "Unlocked"} {
+		if err := fsm.Trigger(state, "Reset"); err != nil {
+			t.Fatalf("trigger err: %v", err)
+		}
+	}
+	if got := fmt.Sprint(changes, p.calls); got != "[false true] [action:reset exit:Unlocked action:reset enter:Locked]" {
+		t.Errorf("expected the reset in Locked to be a self-transition, got %s", got)
+	}
+}
+
+func TestReachableCount(t *testing.T) {
+	if n := initFSM().ReachableCount("Locked"); n != 2 {
+		t.Errorf("expected both turnstile states to be reachable from Locked, got %d", n)
+	}
+
+	fsm := NewStateMachine(nil,
+		Transition{From: "Draft", Event: "Submit", To: "Review"},
+		Transition{From: "Review", Event: "Approve", To: "Published"},
+		Transition{From: "Archived", Event: "Restore", To: "Draft"},
+	)
+	if n := fsm.ReachableCount("Draft"); n != 3 {
+		t.Errorf("expected 3 states reachable from Draft, got %d", n)
+	}
+	if n := fsm.ReachableCount("Unknown"); n != 0 {
+		t.Errorf("expected no states reachable from an unknown state, got %d", n)
+	}
+}
+
+func TestRandomWalk(t *testing.T) {
+	fsm := NewStateMachine(nil,
+		Transition{From: "Locked", Event: "Coin", To: "Unlocked"},
+		Transition{From: "Locked", Event: "Kick", To: "Broken"},
+		Transition{From: "Locked", Event: "Push", To: "Locked", Guard: func(from, event string, args []interface{}) bool { return false }},
+		Transition{From: "Unlocked", Event: "Push", To: "Locked"},
+		Transition{From: "Unlocked", Event: "Coin", To: "Unlocked"},
+	)
+
+	for seed := int64(0); seed < 20; seed++ {
+		events, final := fsm.RandomWalk("Locked", 10, rand.New(rand.NewSource(seed)))
+		again, _ := fsm.RandomWalk("Locked", 10, rand.New(rand.NewSource(seed)))
+		if fmt.Sprint(events) != fmt.Sprint(again) {
+			t.Fatalf("expected seed %d to repeat the walk %v, got %v", seed, events, again)
+		}
+
+		state := "Locked"
+		for _, event := range events {
+			trans, ok := fsm.FindTransition(state, event)
+			if !ok {
+				t.Fatalf("walk %v has no transition for %s in %s", events, event, state)
+			}
+			state = trans.To
+		}
+		if state != final {
+			t.Errorf("expected walk %v to end in %s, got %s", events, state, final)
+		}
+		if len(events) < 10 && final != "Broken" {
+			t.Errorf("expected walk %v to stop early only in the dead end, got %s", events, final)
+		}
+	}
+
+	if events, final := fsm.RandomWalk("Broken", 5, rand.New(rand.NewSource(1))); len(events) != 0 || final != "Broken" {
+		t.Errorf("expected no events from a dead end, got %v, %s", events, final)
+	}
+	if events, final := fsm.RandomWalk("Unlocked", 3, nil); len(events) == 0 || len(events) < 3 && final != "Broken" {
+		t.Errorf("expected a nil rng to use the global source, got %v, %s", events, final)
+	}
+}
@@ -0,0 +1,157 @@
+package fsm
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// TimeInStateGuard returns a guard that passes when the object has been in its current state for at least min.
+// Since the state machine is stateless, enteredAt gets the time the object entered the state from the args,
+// e.g. from a field of the object. The elapsed time is measured with clock, RealClock if it is nil;
+// pass the clock of WithClock so tests can use a fake clock.
+func TimeInStateGuard(clock Clock, min time.Duration, enteredAt func(args []interface{}) time.Time) Guard {
+	if clock == nil {
+		clock = RealClock{}
+	}
+	return func(from string, event string, args []interface{}) bool {
+		return clock.Now().Sub(enteredAt(args)) >= min
+	}
+}
+
+// And returns a guard that passes when all guards pass, it stops at the first guard that rejects.
+// A nil guard passes like a transition without guard, and And() always passes.
+func And(guards ...Guard) Guard {
+	return func(from string, event string, args []interface{}) bool {
+		for _, g := range guards {
+			if g != nil && !g(from, event, args) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or returns a guard that passes when any guard passes, it stops at the first guard that passes.
+// A nil guard passes like a transition without guard, and Or() always rejects.
+func Or(guards ...Guard) Guard {
+	return func(from string, event string, args []interface{}) bool {
+		for _, g := range guards {
+			if g == nil || g(from, event, args) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not returns a guard that passes when the guard rejects. A nil guard passes, so Not(nil) always rejects.
+func Not(guard Guard) Guard {
+	return func(from string, event string, args []interface{}) bool {
+		return guard != nil && !guard(from, event, args)
+	}
+}
+
+// ContextGuardOf returns a ContextGuard that ignores the context and calls g, e.g. to combine a Guard with
+// a CachedGuard in AndContext.
+func ContextGuardOf(g Guard) ContextGuard {
+	if g == nil {
+		return nil
+	}
+	return func(ctx context.Context, from string, event string, args []interface{}) bool {
+		return g(from, event, args)
+	}
+}
+
+// AndContext is And for context guards, the context is passed to each guard.
+func AndContext(guards ...ContextGuard) ContextGuard {
+	return func(ctx context.Context, from string, event string, args []interface{}) bool {
+		for _, g := range guards {
+			if g != nil && !g(ctx, from, event, args) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// OrContext is Or for context guards, the context is passed to each guard.
+func OrContext(guards ...ContextGuard) ContextGuard {
+	return func(ctx context.Context, from string, event string, args []interface{}) bool {
+		for _, g := range guards {
+			if g == nil || g(ctx, from, event, args) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// NotContext is Not for context guards. A nil guard passes, so NotContext(nil) always rejects.
+func NotContext(guard ContextGuard) ContextGuard {
+	return func(ctx context.Context, from string, event string, args []interface{}) bool {
+		return guard != nil && !guard(ctx, from, event, args)
+	}
+}
+
+// guardCacheKey is the context key of the guardCache of CachedGuard.
+type guardCacheKey struct{}
+
+// guardCache holds the results of the cached guards of one match.
+type guardCache struct {
+	mu      sync.Mutex
+	results map[string]*cachedResult
+}
+
+type cachedResult struct {
+	once sync.Once
+	pass bool
+}
+
+func withGuardCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, guardCacheKey{}, &guardCache{})
+}
+
+// CachedGuard returns a guard that shares its result with the cached guards of the same key while an event is
+// matched, so an expensive guard used by several candidate transitions is evaluated once per Trigger and follow-up
+// event. Set it as the ContextGuard of the transitions, the cache is passed by matchers that implement
+// ContextMatcher, like LinearMatcher and ParallelMatcher. Without the cache, g is evaluated each time.
+// It is a ContextGuard since the cache is scoped by the context, combine it with AndContext, OrContext and NotContext.
+func CachedGuard(key string, g Guard) ContextGuard {
+	return func(ctx context.Context, from string, event string, args []interface{}) bool {
+		cache, ok := ctx.Value(guardCacheKey{}).(*guardCache)
+		if !ok {
+			return g(from, event, args)
+		}
+
+		cache.mu.Lock()
+		if cache.results == nil {
+			cache.results = make(map[string]*cachedResult)
+		}
+		r, ok := cache.results[key]
+		if !ok {
+			r = &cachedResult{}
+			cache.results[key] = r
+		}
+		cache.mu.Unlock()
+
+		r.once.Do(func() { r.pass = g(from, event, args) })
+		return r.pass
+	}
+}
+
+// ChaosGuard returns a guard that passes with probability p, e.g. to inject random failures with OnGuardFail when
+// stress testing error handling. A seeded rng makes the failures reproducible, a nil rng uses the global source
+// of math/rand. The guard is safe for concurrent use.
+func ChaosGuard(p float64, rng *rand.Rand) Guard {
+	var mu sync.Mutex
+	return func(from string, event string, args []interface{}) bool {
+		if rng == nil {
+			return rand.Float64() < p
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return rng.Float64() < p
+	}
+}
@@ -0,0 +1,256 @@
+package fsm
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func hasCredit(from string, event string, args []interface{}) bool {
+	return len(args) > 0 && args[0].(*Turnstile).CoinCount > 0
+}
+
+func TestGuard(t *testing.T) {
+	fsm := NewStateMachine(&DefaultDelegate{P: &recordingProcessor{}},
+		Transition{From: "Locked", Event: "Push", To: "Unlocked", Action: "pass-credit", Guard: hasCredit, GuardName: "hasCredit"},
+		Transition{From: "Locked", Event: "Push", To: "Locked", Action: "invalid-push"},
+	)
+
+	ts := &Turnstile{ID: 1, State: "Locked"}
+	if trans, _ := fsm.FindTransition("Locked", "Push", ts); trans.Action != "invalid-push" {
+		t.Errorf("expected the guard to reject, got %v", trans)
+	}
+
+	ts.CoinCount = 1
+	if trans, _ := fsm.FindTransition("Locked", "Push", ts); trans.Action != "pass-credit" {
+		t.Errorf("expected the guard to pass, got %v", trans)
+	}
+	if trans, _ := fsm.FindTransition("Locked", "Push", ts); trans.String() != "Locked --(Push [hasCredit]/pass-credit)--> Unlocked" {
+		t.Errorf("unexpected string: %s", trans)
+	}
+}
+
+func TestGuards(t *testing.T) {
+	always := func(from string, event string, args []interface{}) bool { return true }
+	fsm := NewStateMachine(nil,
+		Transition{From: "A", Event: "e1", To: "B", Guard: hasCredit, GuardName: "hasCredit"},
+		Transition{From: "A", Event: "e2", To: "C", Guard: always, GuardName: "always"},
+		Transition{From: "B", Event: "e1", To: "C", Guard: hasCredit, GuardName: "hasCredit"},
+		Transition{From: "C", Event: "e1", To: "A"},
+	)
+
+	if fmt.Sprint(fsm.Guards()) != "[hasCredit always]" {
+		t.Errorf("unexpected guards: %v", fsm.Guards())
+	}
+}
+
+func TestMergeUnnamedGuards(t *testing.T) {
+	a := NewStateMachine(nil, Transition{From: "Locked", Event: "Push", To: "Unlocked", Guard: hasCredit})
+	b := NewStateMachine(nil, Transition{From: "Locked", Event: "Push", To: "Locked", Guard: hasCredit})
+
+	fsm, err := Merge(nil, a, b)
+	if err != nil {
+		t.Fatalf("merge err: %v", err)
+	}
+	if len(fsm.transitions) != 2 {
+		t.Errorf("expected both guarded transitions, got %v", fsm.transitions)
+	}
+}
+
+func TestTypedTransitionGuard(t *testing.T) {
+	var deprecated int
+	fsm := NewTypedStateMachine(&DefaultDelegate{P: &recordingProcessor{}},
+		TypedTransition[turnstileState, turnstileEvent]{From: stateLocked, Event: eventPush, To: stateUnlocked, Action: "pass-credit",
+			Guard: hasCredit, GuardName: "hasCredit", Deprecated: true},
+		TypedTransition[turnstileState, turnstileEvent]{From: stateLocked, Event: eventPush, To: stateLocked, Action: "invalid-push"},
+	)
+	fsm.With(WithDeprecationHandler(func(Transition) { deprecated++ }))
+
+	ts := &Turnstile{ID: 1, State: "Locked", CoinCount: 1}
+	if err := fsm.Trigger(stateLocked, eventPush, ts); err != nil {
+		t.Fatalf("trigger err: %v", err)
+	}
+	if deprecated != 1 || fmt.Sprint(fsm.Guards()) != "[hasCredit]" {
+		t.Errorf("expected the typed guard and deprecation to be kept, got %d, %v", deprecated, fsm.Guards())
+	}
+}
+
+func TestOnGuardFail(t *testing.T) {
+	p := &recordingProcessor{}
+	fsm := NewStateMachine(&DefaultDelegate{P: p},
+		Transition{From: "Locked", Event: "Push", To: "Unlocked", Action: "pass-credit", Guard: hasCredit, GuardName: "hasCredit",
+			OnGuardFail: "Alarm"},
+	)
+
+	if err := fsm.Trigger("Locked", "Push", &Turnstile{CoinCount: 1}); err != nil {
+		t.Fatalf("trigger err: %v", err)
+	}
+	if err := fsm.Trigger("Locked", "Push", &Turnstile{}); err != nil {
+		t.Fatalf("expected the rejected guard to route to Alarm, got %v", err)
+	}
+
+	want := "[exit:Locked action:pass-credit enter:Unlocked exit:Locked enter:Alarm]"
+	if got := fmt.Sprint(p.calls); got != want {
+		t.Errorf("expected calls %s, got %s", want, got)
+	}
+}
+
+func TestTimeInStateGuard(t *testing.T) {
+	clock := newFakeClock()
+	type order struct {
+		enteredAt time.Time
+	}
+	settled := TimeInStateGuard(clock, 5*time.Second, func(args []interface{}) time.Time {
+		return args[0].(*order).enteredAt
+	})
+	fsm := NewStateMachine(&DefaultDelegate{P: &recordingProcessor{}},
+		Transition{From: "Placed", Event: "Ship", To: "Shipped", Action: "ship", Guard: settled, GuardName: "settled"},
+	).With(WithClock(clock))
+
+	o := &order{enteredAt: clock.Now()}
+	clock.Advance(4 * time.Second)
+	if err := fsm.Trigger("Placed", "Ship", o); err == nil {
+		t.Error("expected the guard to reject before 5s in state")
+	}
+	clock.Advance(time.Second)
+	if err := fsm.Trigger("Placed", "Ship", o); err != nil {
+		t.Errorf("expected the guard to pass after 5s in state, got %v", err)
+	}
+}
+
+func TestGuardCombinators(t *testing.T) {
+	isPush := func(from string, event string, args []interface{}) bool { return event == "Push" }
+	vip := func(from string, event string, args []interface{}) bool { return args[0].(*Turnstile).ID == 0 }
+	fsm := NewStateMachine(nil,
+		Transition{From: "Locked", Event: Wildcard, To: "Unlocked", Guard: And(isPush, Or(hasCredit, vip)), GuardName: "pass"},
+		Transition{From: "Locked", Event: Wildcard, To: "Alarm", Guard: Not(isPush), GuardName: "notPush"},
+	)
+
+	tests := []struct {
+		event string
+		ts    *Turnstile
+		want  string
+	}{
+		{"Push", &Turnstile{ID: 1, CoinCount: 1}, "Unlocked"},
+		{"Push", &Turnstile{ID: 0}, "Unlocked"},
+		{"Push", &Turnstile{ID: 1}, ""},
+		{"Kick", &Turnstile{ID: 1, CoinCount: 1}, "Alarm"},
+	}
+	for _, tt := range tests {
+		var got string
+		if trans, ok := fsm.FindTransition("Locked", tt.event, tt.ts); ok {
+			got = trans.To
+		}
+		if got != tt.want {
+			t.Errorf("%s %+v: expected %q, got %q", tt.event, tt.ts, tt.want, got)
+		}
+	}
+	if !And()("Locked", "Push", nil) || Or()("Locked", "Push", nil) {
+		t.Error("expected And() to pass and Or() to reject")
+	}
+	if Not(nil)("Locked", "Push", nil) || !And(nil)("Locked", "Push", nil) || !Or(nil)("Locked", "Push", nil) {
+		t.Error("expected a nil guard to pass in And and Or, and Not(nil) to reject")
+	}
+}
+
+func TestGuardReceivesEvent(t *testing.T) {
+	var events []string
+	isPayment := func(from string, event string, args []interface{}) bool {
+		events = append(events, event)
+		return event == "Coin" || event == "Card"
+	}
+	fsm := NewStateMachine(nil,
+		Transition{From: "Locked", Event: Wildcard, To: "Unlocked", Guard: isPayment, GuardName: "isPayment"},
+		Transition{From: "Locked", Event: Wildcard, To: "Locked"},
+	)
+
+	for event, want := range map[string]string{"Coin": "Unlocked", "Card": "Unlocked", "Push": "Locked"} {
+		if trans, _ := fsm.FindTransition("Locked", event); trans.To != want {
+			t.Errorf("%s: expected %s, got %v", event, want, trans)
+		}
+	}
+	for _, e := range events {
+		if e == Wildcard {
+			t.Errorf("expected the guard to receive the triggered events, got %v", events)
+		}
+	}
+}
+
+func TestCachedGuard(t *testing.T) {
+	runs := 0
+	credit := CachedGuard("credit", func(from string, event string, args []interface{}) bool {
+		runs++
+		return false
+	})
+	fsm := NewStateMachine(nil,
+		Transition{From: "Locked", Event: "Push", To: "Unlocked", ContextGuard: credit, GuardName: "credit"},
+		Transition{From: "Locked", Event: Wildcard, To: "Open", ContextGuard: credit, GuardName: "credit"},
+		Transition{From: "Locked", Event: Wildcard, To: "Locked"},
+	)
+
+	if err := fsm.Trigger("Locked", "Push"); err != nil {
+		t.Fatalf("trigger err: %v", err)
+	}
+	if runs != 1 {
+		t.Errorf("expected the guard to run once for both candidates, ran %d times", runs)
+	}
+	if err := fsm.Trigger("Locked", "Push"); err != nil || runs != 2 {
+		t.Errorf("expected the guard to run again for the next Trigger, ran %d times: %v", runs, err)
+	}
+}
+
+func TestContextGuardCombinators(t *testing.T) {
+	runs := 0
+	credit := CachedGuard("credit", func(from string, event string, args []interface{}) bool {
+		runs++
+		return args[0].(*Turnstile).CoinCount > 0
+	})
+	vip := func(from string, event string, args []interface{}) bool { return args[0].(*Turnstile).ID == 0 }
+	fsm := NewStateMachine(nil,
+		Transition{From: "Locked", Event: "Push", To: "Unlocked", ContextGuard: OrContext(credit, ContextGuardOf(vip))},
+		Transition{From: "Locked", Event: "Push", To: "Alarm", ContextGuard: AndContext(NotContext(credit), ContextGuardOf(Not(vip)))},
+	)
+
+	tests := []struct {
+		ts   *Turnstile
+		want string
+		runs int
+	}{
+		{&Turnstile{ID: 1, CoinCount: 1}, "Unlocked", 1},
+		{&Turnstile{ID: 0}, "Unlocked", 1},
+		{&Turnstile{ID: 1}, "Alarm", 1},
+	}
+	for _, tt := range tests {
+		runs = 0
+		trans, _ := fsm.FindTransition("Locked", "Push", tt.ts)
+		if trans == nil || trans.To != tt.want || runs != tt.runs {
+			t.Errorf("%+v: expected %s with %d guard runs, got %v with %d", tt.ts, tt.want, tt.runs, trans, runs)
+		}
+	}
+	if NotContext(nil)(context.Background(), "Locked", "Push", nil) || ContextGuardOf(nil) != nil {
+		t.Error("expected NotContext(nil) to reject and ContextGuardOf(nil) to be nil")
+	}
+}
+
+func TestChaosGuard(t *testing.T) {
+	guard := ChaosGuard(0.3, rand.New(rand.NewSource(1)))
+	passed := 0
+	const trials = 10000
+	for i := 0; i < trials; i++ {
+		if guard("Locked", "Coin", nil) {
+			passed++
+		}
+	}
+	if rate := float64(passed) / trials; rate < 0.28 || rate > 0.32 {
+		t.Errorf("expected a pass rate of about 0.3, got %v", rate)
+	}
+
+	fsm := NewStateMachine(nil,
+		Transition{From: "Locked", Event: "Coin", To: "Unlocked", Guard: ChaosGuard(0, nil), GuardName: "chaos", OnGuardFail: "Broken"},
+	)
+	if trans, _ := fsm.FindTransition("Locked", "Coin"); trans != nil {
+		t.Errorf("expected a chaos guard with p=0 to reject, got %v", trans)
+	}
+}
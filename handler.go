@@ -0,0 +1,59 @@
+package fsm
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+)
+
+// Handler returns a http.Handler that renders the state diagram with graphviz, e.g. to inspect the machine during
+// development. The format query parameter selects svg (the default) or png, and the state query parameter
+// highlights a state, see ExportHighlight. It responds with 500 and the reason if graphviz is not installed.
+func (m *StateMachine) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		format := r.URL.Query().Get("format")
+		var contentType string
+		switch format {
+		case "", "svg":
+			format, contentType = "svg", "image/svg+xml"
+		case "png":
+			contentType = "image/png"
+		default:
+			http.Error(w, fmt.Sprintf("unsupported format %q", format), http.StatusBadRequest)
+			return
+		}
+
+		var dot strings.Builder
+		if err := m.ExportHighlight(&dot, r.URL.Query().Get("state")); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		image, err := render(dot.String(), format)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Write(image)
+	})
+}
+
+// render runs graphviz with the dot source as stdin and returns the rendered image.
+func render(dot string, format string) ([]byte, error) {
+	path, err := exec.LookPath("dot")
+	if err != nil {
+		return nil, fmt.Errorf("state machine error: graphviz is not installed: %v", err)
+	}
+
+	var out, stderr bytes.Buffer
+	cmd := exec.Command(path, "-T"+format)
+	cmd.Stdin = strings.NewReader(dot)
+	cmd.Stdout, cmd.Stderr = &out, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out.Bytes(), nil
+}
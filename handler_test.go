@@ -0,0 +1,39 @@
+package fsm
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	srv := httptest.NewServer(initFSM().Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "?format=gif")
+	if err != nil {
+		t.Fatalf("get err: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unsupported format, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(srv.URL + "?state=Locked")
+	if err != nil {
+		t.Fatalf("get err: %v", err)
+	}
+	resp.Body.Close()
+
+	if _, err := exec.LookPath("dot"); err != nil {
+		if resp.StatusCode != http.StatusInternalServerError {
+			t.Errorf("expected 500 without graphviz, got %d", resp.StatusCode)
+		}
+		return
+	}
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "image/svg+xml") {
+		t.Errorf("expected a svg diagram, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+}
@@ -0,0 +1,38 @@
+package fsm
+
+// HistoryRecorder wraps an EventProcessor and records each entered state into the history of the processing object,
+// so processors don't need to maintain the history themselves.
+// Record appends the state to the history of the object carried by args, it is called after the wrapped OnEnter.
+// A nil Record records nothing.
+type HistoryRecorder struct {
+	EventProcessor
+	Record func(toState string, args []interface{})
+}
+
+// NewHistoryRecorder creates a HistoryRecorder.
+func NewHistoryRecorder(p EventProcessor, record func(toState string, args []interface{})) *HistoryRecorder {
+	return &HistoryRecorder{EventProcessor: p, Record: record}
+}
+
+// ActionContext implements ContextProcessor interface and forwards to the wrapped processor.
+func (r *HistoryRecorder) ActionContext(c *EventContext) error {
+	return runAction(r.EventProcessor, c)
+}
+
+// CanEnter implements EntryVetoer interface and forwards to the wrapped processor.
+func (r *HistoryRecorder) CanEnter(toState string, args []interface{}) error {
+	return canEnter(r.EventProcessor, toState, args)
+}
+
+// OnEnter implements EventProcessor interface and records the entered state.
+func (r *HistoryRecorder) OnEnter(toState string, args []interface{}) {
+	r.EventProcessor.OnEnter(toState, args)
+	if r.Record != nil {
+		r.Record(toState, args)
+	}
+}
+
+// OnReset implements ArgsResetter interface and forwards to the wrapped processor.
+func (r *HistoryRecorder) OnReset(args []interface{}) {
+	reset(r.EventProcessor, args)
+}
@@ -0,0 +1,47 @@
+package fsm
+
+import (
+	"fmt"
+	"testing"
+)
+
+// statefulProcessor only keeps the current state of the turnstile.
+type statefulProcessor struct {
+	recordingProcessor
+}
+
+func (p *statefulProcessor) OnEnter(toState string, args []interface{}) {
+	args[0].(*Turnstile).State = toState
+}
+
+func TestHistoryRecorder(t *testing.T) {
+	recorder := NewHistoryRecorder(&statefulProcessor{}, func(toState string, args []interface{}) {
+		ts := args[0].(*Turnstile)
+		ts.States = append(ts.States, toState)
+	})
+	fsm := NewStateMachine(&DefaultDelegate{P: recorder}, initFSM().transitions...)
+
+	ts := &Turnstile{ID: 1, State: "Locked", States: []string{"Locked"}}
+	for _, event := range []string{"Push", "Push", "Coin", "Push", "Push"} {
+		if err := fsm.Trigger(ts.State, event, ts); err != nil {
+			t.Fatalf("trigger err: %v", err)
+		}
+	}
+
+	if fmt.Sprint(ts.States) != "[Locked Unlocked Locked]" {
+		t.Errorf("unexpected history: %v", ts.States)
+	}
+}
+
+func TestHistoryRecorderNilRecord(t *testing.T) {
+	p := &recordingProcessor{}
+	fsm := NewStateMachine(&DefaultDelegate{P: NewHistoryRecorder(p, nil)},
+		Transition{From: "Locked", Event: "Coin", To: "Unlocked", Action: "check"},
+	)
+	if err := fsm.Trigger("Locked", "Coin"); err != nil {
+		t.Fatalf("trigger err: %v", err)
+	}
+	if p.calls[len(p.calls)-1] != "enter:Unlocked" {
+		t.Errorf("expected OnEnter to be forwarded, got %v", p.calls)
+	}
+}
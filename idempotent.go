@@ -0,0 +1,39 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+)
+
+// SeenStore remembers the IDs of processed events for TriggerIdempotent, e.g. in a database table with a unique key.
+// The store must be safe for concurrent use if events are triggered concurrently.
+type SeenStore interface {
+	// Seen reports whether the event has been processed.
+	Seen(id string) bool
+	// Mark records that the event has been processed.
+	Mark(id string)
+}
+
+var errNoSeenStore = errors.New("state machine error: TriggerIdempotent needs WithSeenStore")
+
+// TriggerIdempotent fires a event like Trigger unless the event with the ID has been processed already, e.g. for
+// at-least-once queues that deliver events again. A duplicate event returns nil without calling the delegate.
+// The event is marked as processed once its transition has completed, also if a follow-up event failed after that,
+// since processing it again would run its action again. A failed transition is not marked, so it can be retried.
+// Seen and Mark are separate calls, so concurrent deliveries of the same event may both be processed.
+// It returns an error if WithSeenStore is not configured.
+func (m *StateMachine) TriggerIdempotent(eventID string, currentState string, event string, args ...interface{}) error {
+	if m.seenStore == nil {
+		return errNoSeenStore
+	}
+	if m.seenStore.Seen(eventID) {
+		return nil
+	}
+
+	_, err := m.trigger(currentState, event, args, m.observe(context.Background(), nil))
+	var followUpErr *FollowUpError
+	if err == nil || errors.As(err, &followUpErr) {
+		m.seenStore.Mark(eventID)
+	}
+	return err
+}
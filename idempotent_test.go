@@ -0,0 +1,38 @@
+package fsm
+
+import "testing"
+
+// memorySeenStore is an in-memory SeenStore.
+type memorySeenStore map[string]bool
+
+func (s memorySeenStore) Seen(id string) bool { return s[id] }
+
+func (s memorySeenStore) Mark(id string) { s[id] = true }
+
+func TestTriggerIdempotent(t *testing.T) {
+	p := &recordingProcessor{}
+	fsm := NewStateMachine(&DefaultDelegate{P: p},
+		Transition{From: "Locked", Event: "Coin", To: "Unlocked", Action: "check"},
+	)
+	if err := fsm.TriggerIdempotent("evt-1", "Locked", "Coin"); err == nil {
+		t.Fatal("expected an error without SeenStore")
+	}
+
+	store := memorySeenStore{}
+	fsm.With(WithSeenStore(store))
+	for i := 0; i < 2; i++ {
+		if err := fsm.TriggerIdempotent("evt-1", "Locked", "Coin"); err != nil {
+			t.Fatalf("trigger err: %v", err)
+		}
+	}
+	if len(p.calls) != 3 || !store["evt-1"] {
+		t.Errorf("expected the duplicate event to be skipped, got %v", p.calls)
+	}
+
+	if err := fsm.TriggerIdempotent("evt-2", "Unlocked", "Coin"); err == nil {
+		t.Fatal("expected an error for a missing transition")
+	}
+	if store["evt-2"] {
+		t.Error("expected a failed event not to be marked")
+	}
+}
@@ -0,0 +1,52 @@
+package fsm
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// jgfDocument is a JSON Graph Format (https://jsongraphformat.info) version 2 document.
+type jgfDocument struct {
+	Graph jgfGraph `json:"graph"`
+}
+
+type jgfGraph struct {
+	Directed bool               `json:"directed"`
+	Nodes    map[string]jgfNode `json:"nodes"`
+	Edges    []jgfEdge          `json:"edges"`
+}
+
+type jgfNode struct {
+	Label string `json:"label"`
+}
+
+type jgfEdge struct {
+	Source   string          `json:"source"`
+	Target   string          `json:"target"`
+	Relation string          `json:"relation"`
+	Metadata jgfEdgeMetadata `json:"metadata"`
+}
+
+type jgfEdgeMetadata struct {
+	Event      string `json:"event"`
+	Action     string `json:"action,omitempty"`
+	Guard      string `json:"guard,omitempty"`
+	Deprecated bool   `json:"deprecated,omitempty"`
+}
+
+// ExportJGF writes the state diagram in JSON Graph Format version 2, which graph tools like Cytoscape read.
+// States are the nodes, keyed by name, and transitions are the edges with the event as relation and the event,
+// action and guard name as metadata.
+func (m *StateMachine) ExportJGF(w io.Writer) error {
+	g := jgfGraph{Directed: true, Nodes: make(map[string]jgfNode), Edges: []jgfEdge{}}
+	for _, t := range m.transitions {
+		g.Nodes[t.From] = jgfNode{Label: t.From}
+		g.Nodes[t.To] = jgfNode{Label: t.To}
+		g.Edges = append(g.Edges, jgfEdge{Source: t.From, Target: t.To, Relation: t.Event,
+			Metadata: jgfEdgeMetadata{Event: t.Event, Action: t.Action, Guard: t.GuardName, Deprecated: t.Deprecated}})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(jgfDocument{Graph: g})
+}
@@ -0,0 +1,22 @@
+package fsm
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestExportJGF(t *testing.T) {
+	var out strings.Builder
+	if err := initFSM().ExportJGF(&out); err != nil {
+		t.Fatalf("export err: %v", err)
+	}
+
+	golden, err := os.ReadFile("testdata/turnstile.jgf.json")
+	if err != nil {
+		t.Fatalf("read golden file: %v", err)
+	}
+	if out.String() != string(golden) {
+		t.Errorf("expected\n%s\ngot\n%s", golden, out.String())
+	}
+}
@@ -0,0 +1,162 @@
+package fsm
+
+import "fmt"
+
+// IsFinal reports whether the state is declared final with WithFinalStates.
+func (m *StateMachine) IsFinal(state string) bool {
+	return m.finalStates[state]
+}
+
+// IsFinalFor reports whether the state is final for the processing object carried by args: it is declared final with
+// WithFinalStates or the predicate of WithFinalPredicate reports it final, e.g. "Closed" is final unless reopening
+// is allowed for the object.
+func (m *StateMachine) IsFinalFor(state string, args ...interface{}) bool {
+	return m.IsFinal(state) || m.finalPredicate != nil && m.finalPredicate(state, args)
+}
+
+// DeadEnds returns the states that are entered but have no transition out of them and are not declared final,
+// in definition order. Objects get stuck in them, so they are usually modeling mistakes.
+func (m *StateMachine) DeadEnds() []string {
+	var states []string
+	seen := map[string]bool{Wildcard: true}
+	for _, t := range m.transitions {
+		if seen[t.To] {
+			continue
+		}
+		seen[t.To] = true
+		if _, out := m.Degree(t.To); out == 0 && !m.IsFinal(t.To) {
+			states = append(states, t.To)
+		}
+	}
+	return states
+}
+
+// NewStrictStateMachine creates a new state machine like NewStateMachine, but it returns an error if two unguarded
+// transitions have the same From and Event, so nondeterministic tables are rejected up front.
+// Guarded transitions can't be checked since guards can't be compared.
+func NewStrictStateMachine(delegate Delegate, transitions ...Transition) (*StateMachine, error) {
+	m := NewStateMachine(delegate, transitions...)
+	if errs := nondeterministic(m.transitions); len(errs) > 0 {
+		return nil, errs[0]
+	}
+	return m, nil
+}
+
+// nondeterministic returns an error per unguarded transition that has the same From and Event as an earlier one.
+func nondeterministic(transitions []Transition) []error {
+	var errs []error
+	seen := make(map[[2]string]Transition)
+	for _, t := range transitions {
+		if t.guarded() {
+			continue
+		}
+		key := [2]string{t.From, t.Event}
+		if old, ok := seen[key]; ok {
+			errs = append(errs, fmt.Errorf("state machine error: nondeterministic transitions %s and %s", old, t))
+			continue
+		}
+		seen[key] = t
+	}
+	return errs
+}
+
+// LintOption configures the optional checks of Lint.
+type LintOption func(*lintConfig)
+
+type lintConfig struct {
+	maxOutDegree int
+}
+
+// MaxOutDegree makes Lint report the states with more than max transitions out of them, see Degree, since states
+// with many transitions are hard to review. Zero disables the check.
+func MaxOutDegree(max int) LintOption {
+	return func(c *lintConfig) {
+		c.maxOutDegree = max
+	}
+}
+
+// Lint checks the transitions for likely modeling mistakes and returns an error per finding, nil if there is none.
+// It reports unguarded transitions with the same From and Event, see NewStrictStateMachine, and dead ends,
+// see DeadEnds, and the findings of the optional checks configured by opts.
+func (m *StateMachine) Lint(opts ...LintOption) []error {
+	var config lintConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	errs := nondeterministic(m.transitions)
+	for _, s := range m.DeadEnds() {
+		errs = append(errs, fmt.Errorf("state machine lint: state %s is a dead end, it has no transition out and is not final", s))
+	}
+	if config.maxOutDegree > 0 {
+		for _, s := range m.states() {
+			if _, out := m.Degree(s); out > config.maxOutDegree {
+				errs = append(errs, fmt.Errorf("state machine lint: state %s has %d transitions out, more than %d", s, out, config.maxOutDegree))
+			}
+		}
+	}
+	return errs
+}
+
+// CheckWellFormed checks that the transitions form a connected workflow: every state is reachable from the initial
+// state and every state that is not final can reach a final state. If finals is empty, the states declared with
+// WithFinalStates are final. It returns an error per violation in definition order of the states, nil if there is
+// none. Guards are not evaluated, so it is a structural guarantee, stronger than Lint.
+func (m *StateMachine) CheckWellFormed(initial string, finals ...string) []error {
+	states := m.states()
+	if !contains(states, initial) {
+		return []error{fmt.Errorf("state machine error: initial state %s is not a state of the transitions", initial)}
+	}
+
+	final := make(map[string]bool)
+	for _, s := range finals {
+		final[s] = true
+	}
+	if len(finals) == 0 {
+		final = m.finalStates
+	}
+
+	var errs []error
+	var queue []string
+	finishing := make(map[string]bool)
+	for _, s := range states {
+		if final[s] {
+			finishing[s] = true
+			queue = append(queue, s)
+		}
+	}
+	for _, s := range finals {
+		if !contains(states, s) {
+			errs = append(errs, fmt.Errorf("state machine error: final state %s is not a state of the transitions", s))
+		}
+	}
+
+	// walk the transitions backwards from the final states to find the states that can reach one
+	previous := make(map[string][]string)
+	for from, tos := range m.successors(states) {
+		for _, to := range tos {
+			previous[to] = append(previous[to], from)
+		}
+	}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		for _, from := range previous[s] {
+			if !finishing[from] {
+				finishing[from] = true
+				queue = append(queue, from)
+			}
+		}
+	}
+
+	reachable := m.reachable(initial)
+	for _, s := range states {
+		if !reachable[s] {
+			errs = append(errs, fmt.Errorf("state machine error: state %s is not reachable from %s", s, initial))
+		}
+		if !finishing[s] {
+			errs = append(errs, fmt.Errorf("state machine error: state %s can't reach a final state", s))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,124 @@
+package fsm
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDeadEnds(t *testing.T) {
+	fsm := NewStateMachine(nil,
+		Transition{From: "New", Event: "Pay", To: "Paid"},
+		Transition{From: "Paid", Event: "Ship", To: "Shipped"},
+		Transition{From: "Paid", Event: "Hold", To: "OnHold"},
+		Transition{From: "New", Event: "Cancel", To: "Cancelled"},
+	).With(WithFinalStates("Shipped", "Cancelled"))
+
+	if got := fmt.Sprint(fsm.DeadEnds()); got != "[OnHold]" {
+		t.Errorf("expected the dead end OnHold, got %s", got)
+	}
+	if errs := fsm.Lint(); len(errs) != 1 {
+		t.Errorf("expected a lint error, got %v", errs)
+	}
+	if !fsm.IsFinal("Shipped") || fsm.IsFinal("Paid") {
+		t.Error("expected only the declared states to be final")
+	}
+
+	if errs := initFSM().Lint(); len(errs) != 0 {
+		t.Errorf("expected no lint errors for the turnstile, got %v", errs)
+	}
+}
+
+// ticket is a processing object whose Closed state is final unless it can be reopened.
+type ticket struct {
+	reopenable bool
+}
+
+func TestIsFinalFor(t *testing.T) {
+	fsm := NewStateMachine(nil,
+		Transition{From: "Open", Event: "Close", To: "Closed"},
+		Transition{From: "Closed", Event: "Reopen", To: "Open"},
+		Transition{From: "Open", Event: "Reject", To: "Rejected"},
+	).With(WithFinalStates("Rejected"), WithFinalPredicate(func(state string, args []interface{}) bool {
+		return state == "Closed" && !args[0].(*ticket).reopenable
+	}))
+
+	if !fsm.IsFinalFor("Closed", &ticket{}) {
+		t.Error("expected Closed to be final for a ticket that can't be reopened")
+	}
+	if fsm.IsFinalFor("Closed", &ticket{reopenable: true}) {
+		t.Error("expected Closed not to be final for a reopenable ticket")
+	}
+	if !fsm.IsFinalFor("Rejected", &ticket{reopenable: true}) || fsm.IsFinal("Closed") {
+		t.Error("expected only Rejected to be final regardless of args")
+	}
+}
+
+func TestNewStrictStateMachine(t *testing.T) {
+	if _, err := NewStrictStateMachine(nil, initFSM().transitions...); err != nil {
+		t.Errorf("expected the turnstile to be deterministic, got %v", err)
+	}
+
+	_, err := NewStrictStateMachine(nil,
+		Transition{From: "Locked", Event: "Coin", To: "Unlocked", Action: "check"},
+		Transition{From: "Locked", Event: "Coin", To: "Locked", Action: "reject"},
+	)
+	if err == nil {
+		t.Error("expected an error for duplicate unguarded transitions")
+	}
+
+	_, err = NewStrictStateMachine(nil,
+		Transition{From: "Locked", Event: "Push", To: "Unlocked", Action: "pass-credit", Guard: hasCredit},
+		Transition{From: "Locked", Event: "Push", To: "Locked", Action: "invalid-push"},
+	)
+	if err != nil {
+		t.Errorf("expected guarded transitions to be accepted, got %v", err)
+	}
+}
+
+func TestLintMaxOutDegree(t *testing.T) {
+	fsm := NewStateMachine(nil,
+		Transition{From: "Hub", Event: "a", To: "A"},
+		Transition{From: "Hub", Event: "b", To: "B"},
+		Transition{From: "Hub", Event: "c", To: "C"},
+		Transition{From: "Hub", Event: "d", To: "D"},
+		Transition{From: "Hub", Event: "e", To: "Hub"},
+	).With(WithFinalStates("A", "B", "C", "D"))
+
+	if errs := fsm.Lint(); len(errs) != 0 {
+		t.Errorf("expected no lint errors without MaxOutDegree, got %v", errs)
+	}
+	errs := fsm.Lint(MaxOutDegree(3))
+	if len(errs) != 1 || errs[0].Error() != "state machine lint: state Hub has 5 transitions out, more than 3" {
+		t.Errorf("expected Hub to be reported, got %v", errs)
+	}
+}
+
+func TestCheckWellFormed(t *testing.T) {
+	fsm := NewStateMachine(nil,
+		Transition{From: "New", Event: "Pay", To: "Paid"},
+		Transition{From: "Paid", Event: "Ship", To: "Shipped"},
+		Transition{From: "Paid", Event: "Dispute", To: "Disputed"},
+		Transition{From: "Disputed", Event: "Review", To: "Disputed"},
+		Transition{From: "Legacy", Event: "Migrate", To: "New"},
+		Transition{From: "New", Event: "Cancel", To: "Cancelled"},
+	).With(WithFinalStates("Shipped", "Cancelled"))
+
+	want := []string{
+		"state machine error: state Disputed can't reach a final state",
+		"state machine error: state Legacy is not reachable from New",
+	}
+	if errs := fsm.CheckWellFormed("New"); fmt.Sprint(errs) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, errs)
+	}
+
+	errs := fsm.CheckWellFormed("Legacy", "Shipped")
+	want = []string{"state machine error: state Disputed can't reach a final state",
+		"state machine error: state Cancelled can't reach a final state"}
+	if fmt.Sprint(errs) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, errs)
+	}
+
+	if errs := fsm.CheckWellFormed("Missing"); len(errs) != 1 {
+		t.Errorf("expected an error for an unknown initial state, got %v", errs)
+	}
+}
@@ -0,0 +1,222 @@
+package fsm
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// definition is the JSON document of transitions. Includes reference other documents whose transitions are merged
+// into this one.
+type definition struct {
+	Version     string       `json:"version,omitempty"`
+	Includes    []string     `json:"$include,omitempty"`
+	Transitions []Transition `json:"transitions"`
+}
+
+// LoadJSON reads transitions from a JSON document. The document is either an array of transitions or an object:
+//
+//	{"transitions": [{"from": "Locked", "event": "Coin", "to": "Unlocked", "action": "check"}]}
+//
+// $include is not supported since there is no base directory to resolve it, use LoadFile instead.
+func LoadJSON(r io.Reader) ([]Transition, error) {
+	def, err := decodeDefinition(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(def.Includes) > 0 {
+		return nil, errors.New("state machine error: $include is only supported by LoadFile")
+	}
+	return def.Transitions, nil
+}
+
+// LoadJSONVersion reads transitions from a JSON document like LoadJSON and returns the version of the document,
+// empty if it has none, e.g. to create the machine with WithVersion.
+func LoadJSONVersion(r io.Reader) ([]Transition, string, error) {
+	def, err := decodeDefinition(r)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(def.Includes) > 0 {
+		return nil, "", errors.New("state machine error: $include is only supported by LoadFile")
+	}
+	return def.Transitions, def.Version, nil
+}
+
+// LoadNamespaced reads transitions from a JSON document like LoadJSON and prefixes their states with the namespace,
+// e.g. "Idle" becomes "auth.Idle", so modules with the same state names can be merged. Events are not prefixed,
+// use Namespace for that.
+func LoadNamespaced(prefix string, r io.Reader) ([]Transition, error) {
+	transitions, err := LoadJSON(r)
+	if err != nil {
+		return nil, err
+	}
+	return Namespace(prefix, false, transitions...), nil
+}
+
+// Namespace returns copies of the transitions whose states, and events if prefixEvents is true, are prefixed with
+// prefix and a dot. Wildcard is not prefixed, so it still matches any state or event.
+func Namespace(prefix string, prefixEvents bool, transitions ...Transition) []Transition {
+	name := func(s string) string {
+		if s == "" || s == Wildcard {
+			return s
+		}
+		return prefix + "." + s
+	}
+
+	namespaced := make([]Transition, 0, len(transitions))
+	for _, t := range transitions {
+		t.From, t.To, t.OnGuardFail, t.OnFailure = name(t.From), name(t.To), name(t.OnGuardFail), name(t.OnFailure)
+		if len(t.Froms) > 0 {
+			froms := make([]string, 0, len(t.Froms))
+			for _, from := range t.Froms {
+				froms = append(froms, name(from))
+			}
+			t.Froms = froms
+		}
+		if prefixEvents {
+			t.Event = name(t.Event)
+		}
+		namespaced = append(namespaced, t)
+	}
+	return namespaced
+}
+
+// LoadFile reads transitions from a JSON file. The object form can reference other files with $include:
+//
+//	{"$include": ["common.json"], "transitions": [...]}
+//
+// Included files are resolved relative to the directory of the including file, and their transitions are merged
+// before the transitions of the including file with the conflict detection of Merge.
+func LoadFile(path string) ([]Transition, error) {
+	return loadFile(path, make(map[string]bool))
+}
+
+func loadFile(path string, loading map[string]bool) ([]Transition, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if loading[abs] {
+		return nil, fmt.Errorf("state machine error: cyclic $include of %s", path)
+	}
+	loading[abs] = true
+	defer delete(loading, abs)
+
+	f, err := os.Open(abs)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	def, err := decodeDefinition(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	lists := make([][]Transition, 0, len(def.Includes)+1)
+	for _, include := range def.Includes {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(abs), include)
+		}
+		transitions, err := loadFile(include, loading)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, transitions)
+	}
+	lists = append(lists, def.Transitions)
+
+	return mergeTransitions(lists...)
+}
+
+// decodeDefinition decodes a definition in the array or object form.
+func decodeDefinition(r io.Reader) (*definition, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var def definition
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &def.Transitions)
+	} else {
+		err = json.Unmarshal(data, &def)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &def, nil
+}
+
+// ExportJSON writes the transitions as a JSON document that LoadJSON reads back, with the version of WithVersion,
+// see LoadJSONVersion.
+func (m *StateMachine) ExportJSON(w io.Writer) error {
+	transitions := m.transitions
+	if transitions == nil {
+		transitions = []Transition{}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(definition{Version: m.version, Transitions: transitions})
+}
+
+// dotEdgeLine matches an edge written by WriteDot, e.g. "Locked" -> "Unlocked" [label="Coin | check"].
+var dotEdgeLine = regexp.MustCompile(`^\s*"((?:[^"\\]|\\.)*)"\s*->\s*"((?:[^"\\]|\\.)*)"\s*\[(.*)\]\s*$`)
+
+// dotLabel matches the label attribute of an edge.
+var dotLabel = regexp.MustCompile(`label="((?:[^"\\]|\\.)*)"`)
+
+// dotWeight matches the weight attribute of an edge.
+var dotWeight = regexp.MustCompile(`\bweight=(\d+)`)
+
+// LoadDOT reads transitions from a graphviz digraph, the inverse of WriteDot, e.g. for machines designed in
+// graphviz first. It supports the subset WriteDot writes: each edge between quoted states is a transition labeled
+// "Event | Action", or "Event\nAction" with WithMultilineLabels, grey dashed edges are deprecated and the weight attribute is the Weight.
+// Other statements are ignored. Guards and edges of composite states can't be recovered.
+func LoadDOT(r io.Reader) ([]Transition, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var transitions []Transition
+	for i, line := range strings.Split(string(data), "\n") {
+		match := dotEdgeLine.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if match == nil {
+			continue
+		}
+		label := dotLabel.FindStringSubmatch(match[3])
+		if label == nil {
+			return nil, fmt.Errorf("state machine error: edge without label at line %d", i+1)
+		}
+		parts := strings.SplitN(label[1], " | ", 2)
+		if len(parts) != 2 {
+			parts = strings.SplitN(label[1], `\n`, 2)
+		}
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("state machine error: label %q at line %d is not Event | Action", label[1], i+1)
+		}
+		t := Transition{From: dotUnescape(match[1]), Event: dotUnescape(parts[0]),
+			To: dotUnescape(match[2]), Action: dotUnescape(parts[1]),
+			Deprecated: strings.Contains(match[3], "style=dashed color=grey")}
+		if weight := dotWeight.FindStringSubmatch(match[3]); weight != nil {
+			t.Weight, _ = strconv.Atoi(weight[1])
+		}
+		transitions = append(transitions, t)
+	}
+	return transitions, nil
+}
+
+// dotUnescape reverts dotEscape.
+func dotUnescape(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\"`, `"`, `\n`, "\n").Replace(s)
+}
@@ -0,0 +1,50 @@
+package fsm
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func FuzzLoadJSON(f *testing.F) {
+	seeds := []string{
+		`{"transitions": [{"from": "Locked", "event": "Coin", "to": "Unlocked", "action": "check"}]}`,
+		`[{"from": "Locked", "event": "Push", "to": "Locked"}]`,
+		`[{"from": "A", "froms": ["B", "C"], "event": "E", "to": "D"}]`,
+		`{"transitions": [{"from": "*", "event": "*", "to": "Error", "deprecated": true}]}`,
+		`{"transitions": []}`,
+		`[]`,
+		`{}`,
+		`null`,
+		``,
+		`[`,
+		`{"transitions": [{"from": 1}]}`,
+		`{"$include": ["other.json"]}`,
+		"[{\"from\": \"\xff\", \"event\": \"\\u0000\"}]",
+	}
+	for _, seed := range seeds {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		transitions, err := LoadJSON(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+
+		// ExportJSON writes the table with Froms expanded into a transition per source state
+		fsm := NewStateMachine(nil, transitions...)
+		expanded := fsm.Transitions()
+		var buf bytes.Buffer
+		if err := fsm.ExportJSON(&buf); err != nil {
+			t.Fatalf("export err: %v", err)
+		}
+		again, err := LoadJSON(&buf)
+		if err != nil {
+			t.Fatalf("reload err: %v\n%s", err, buf.String())
+		}
+		if len(expanded) != len(again) || (len(again) > 0 && !reflect.DeepEqual(expanded, again)) {
+			t.Fatalf("round trip mismatch: %v != %v", expanded, again)
+		}
+	})
+}
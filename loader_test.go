@@ -0,0 +1,117 @@
+package fsm
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLoadJSON(t *testing.T) {
+	transitions, err := LoadJSON(strings.NewReader(`[{"from": "Locked", "event": "Coin", "to": "Unlocked", "action": "check"}]`))
+	if err != nil {
+		t.Fatalf("load err: %v", err)
+	}
+	if fmt.Sprint(transitions) != "[Locked --(Coin/check)--> Unlocked]" {
+		t.Errorf("unexpected transitions: %v", transitions)
+	}
+
+	_, err = LoadJSON(strings.NewReader(`{"$include": ["common.json"], "transitions": []}`))
+	if err == nil {
+		t.Error("expected an error for $include without a file")
+	}
+}
+
+func TestLoadFileInclude(t *testing.T) {
+	transitions, err := LoadFile("testdata/turnstile.json")
+	if err != nil {
+		t.Fatalf("load err: %v", err)
+	}
+
+	want := "[Locked --(Push/invalid-push)--> Locked Unlocked --(Coin/repeat-check)--> Unlocked " +
+		"Locked --(Coin/check)--> Unlocked Unlocked --(Push/pass)--> Locked]"
+	if fmt.Sprint(transitions) != want {
+		t.Errorf("expected %s, got %v", want, transitions)
+	}
+
+	if _, err := LoadFile("testdata/conflict.json"); err == nil {
+		t.Error("expected a conflict error")
+	}
+}
+
+func TestLoadNamespaced(t *testing.T) {
+	auth, err := LoadNamespaced("auth", strings.NewReader(`[
+		{"from": "Idle", "event": "Login", "to": "Authed", "action": "login"},
+		{"from": "Authed", "event": "Logout", "to": "Idle", "action": "logout", "onFailure": "Broken"}]`))
+	if err != nil {
+		t.Fatalf("load err: %v", err)
+	}
+	upload, err := LoadNamespaced("upload", strings.NewReader(`[
+		{"from": "Idle", "event": "Login", "to": "Ready", "action": "prepare"}]`))
+	if err != nil {
+		t.Fatalf("load err: %v", err)
+	}
+
+	fsm, err := Merge(nil, NewStateMachine(nil, auth...), NewStateMachine(nil, upload...))
+	if err != nil {
+		t.Fatalf("expected the namespaced modules to merge, got %v", err)
+	}
+	if trans, ok := fsm.FindTransition("upload.Idle", "Login"); !ok || trans.To != "upload.Ready" {
+		t.Errorf("expected upload.Idle to go to upload.Ready, got %v", trans)
+	}
+	if trans, ok := fsm.FindTransition("auth.Idle", "Login"); !ok || trans.To != "auth.Authed" {
+		t.Errorf("expected auth.Idle to go to auth.Authed, got %v", trans)
+	}
+	if trans, ok := fsm.FindTransition("auth.Authed", "Logout"); !ok || trans.OnFailure != "auth.Broken" {
+		t.Errorf("expected the compensation state to be namespaced, got %v", trans)
+	}
+
+	events := Namespace("auth", true, Transition{From: Wildcard, Event: "Reset", To: "Idle"})
+	if got := fmt.Sprint(events); got != "[* --(auth.Reset)--> auth.Idle]" {
+		t.Errorf("unexpected namespaced transition %s", got)
+	}
+}
+
+func TestLoadJSONVersion(t *testing.T) {
+	var b strings.Builder
+	if err := initFSM().With(WithVersion("2.1")).ExportJSON(&b); err != nil {
+		t.Fatalf("export err: %v", err)
+	}
+
+	transitions, version, err := LoadJSONVersion(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatalf("load err: %v", err)
+	}
+	if version != "2.1" || len(transitions) != 4 {
+		t.Errorf("expected version 2.1 and 4 transitions, got %q and %v", version, transitions)
+	}
+	if NewStateMachine(nil, transitions...).With(WithVersion(version)).Version() != "2.1" {
+		t.Error("expected the version to be set")
+	}
+}
+
+func TestLoadDOT(t *testing.T) {
+	fsm := NewStateMachine(nil,
+		Transition{From: "Locked", Event: "Coin", To: "Unlocked", Action: "check", Weight: 12},
+		Transition{From: "Locked", Event: "Push", To: "Locked"},
+		Transition{From: "Unlocked", Event: `Say "hi"`, To: "Locked", Action: `a\b`, Deprecated: true},
+		Transition{From: Wildcard, Event: "Reset", To: "Locked", Action: "reset"},
+	)
+
+	for _, multiline := range []bool{false, true} {
+		var dot strings.Builder
+		if err := fsm.With(WithMultilineLabels(multiline)).WriteDot(&dot); err != nil {
+			t.Fatalf("write dot err: %v", err)
+		}
+		transitions, err := LoadDOT(strings.NewReader(dot.String()))
+		if err != nil {
+			t.Fatalf("load err: %v", err)
+		}
+		if fmt.Sprint(transitions) != fmt.Sprint(fsm.transitions) || !transitions[2].Deprecated || transitions[0].Weight != 12 {
+			t.Errorf("expected %v, got %v", fsm.transitions, transitions)
+		}
+	}
+
+	if _, err := LoadDOT(strings.NewReader(`"A" -> "B" [label="Coin"]`)); err == nil {
+		t.Error("expected an error for a label without action")
+	}
+}
@@ -0,0 +1,137 @@
+package fsm
+
+import (
+	"context"
+	"sort"
+)
+
+// Matcher finds the transition to process for the current state and event, it returns nil if no transition matches.
+// args are the args passed to Trigger or FindTransition, they are passed to guards.
+type Matcher interface {
+	Match(transitions []Transition, from string, event string, args []interface{}) *Transition
+}
+
+// ContextMatcher is an optional interface a Matcher can implement to pass a context to ContextGuards. The state
+// machine calls MatchContext with a context that carries the cache of CachedGuard for the event being matched.
+type ContextMatcher interface {
+	MatchContext(ctx context.Context, transitions []Transition, from string, event string, args []interface{}) *Transition
+}
+
+// LinearMatcher is the default Matcher. It scans transitions in order and returns the most specific match,
+// see Transition for the precedence of wildcards. Among equally specific matches the first one wins.
+// Transitions whose guard rejects are skipped.
+type LinearMatcher struct{}
+
+// Match implements Matcher interface.
+func (m LinearMatcher) Match(transitions []Transition, from string, event string, args []interface{}) *Transition {
+	return m.MatchContext(context.Background(), transitions, from, event, args)
+}
+
+// MatchContext implements ContextMatcher interface.
+func (LinearMatcher) MatchContext(ctx context.Context, transitions []Transition, from string, event string, args []interface{}) *Transition {
+	var best *Transition
+	bestRank := 0
+	for i := range transitions {
+		rank := matchRank(transitions[i], from, event)
+		if rank > bestRank && passGuardContext(ctx, transitions[i], from, event, args) {
+			best, bestRank = &transitions[i], rank
+		}
+	}
+	return best
+}
+
+// matchRank returns how specific the transition matches the state and event, 0 means it doesn't match.
+func matchRank(t Transition, fromState string, event string) int {
+	switch {
+	case t.From == fromState && t.Event == event:
+		return 4
+	case t.From == fromState && t.Event == Wildcard:
+		return 3
+	case t.From == Wildcard && t.Event == event:
+		return 2
+	case t.From == Wildcard && t.Event == Wildcard:
+		return 1
+	}
+	return 0
+}
+
+// ParallelMatcher is a Matcher for slow guards, e.g. guards doing I/O. It evaluates the guards of all matching
+// transitions concurrently and returns the same transition as LinearMatcher: the most specific passing one, the
+// first one among equally specific ones. Once the result is known, the contexts of the ContextGuards still running
+// are cancelled and Match returns without waiting for them.
+type ParallelMatcher struct{}
+
+// Match implements Matcher interface.
+func (m ParallelMatcher) Match(transitions []Transition, from string, event string, args []interface{}) *Transition {
+	return m.MatchContext(context.Background(), transitions, from, event, args)
+}
+
+// MatchContext implements ContextMatcher interface, the contexts of the guards are derived from ctx.
+func (ParallelMatcher) MatchContext(ctx context.Context, transitions []Transition, from string, event string, args []interface{}) *Transition {
+	type candidate struct {
+		index, rank int
+		cancel      context.CancelFunc
+	}
+	var candidates []*candidate
+	for i := range transitions {
+		if rank := matchRank(transitions[i], from, event); rank > 0 {
+			candidates = append(candidates, &candidate{index: i, rank: rank})
+		}
+	}
+	// candidates are sorted by priority, the first passing one wins.
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].rank > candidates[j].rank })
+
+	type result struct {
+		c    *candidate
+		pass bool
+	}
+	results := make(chan result, len(candidates))
+	for _, c := range candidates {
+		ctx, cancel := context.WithCancel(ctx)
+		c.cancel = cancel
+		if !transitions[c.index].guarded() {
+			results <- result{c, passGuardContext(ctx, transitions[c.index], from, event, args)}
+			continue
+		}
+		go func(ctx context.Context, c *candidate) {
+			results <- result{c, passGuardContext(ctx, transitions[c.index], from, event, args)}
+		}(ctx, c)
+	}
+	defer func() {
+		for _, c := range candidates {
+			c.cancel()
+		}
+	}()
+
+	passed := make(map[*candidate]bool, len(candidates))
+	for range candidates {
+		r := <-results
+		passed[r.c] = r.pass
+		for _, c := range candidates {
+			pass, done := passed[c]
+			if !done {
+				break
+			}
+			if pass {
+				return &transitions[c.index]
+			}
+		}
+	}
+	return nil
+}
+
+// passGuard reports whether the guard of the transition passes, transitions without guard and condition always pass.
+func passGuard(t Transition, from string, event string, args []interface{}) bool {
+	return passGuardContext(context.Background(), t, from, event, args)
+}
+
+// passGuardContext is passGuard with the context passed to the ContextGuard.
+func passGuardContext(ctx context.Context, t Transition, from string, event string, args []interface{}) bool {
+	if !t.guarded() {
+		return t.Condition == ""
+	}
+	if t.Guard != nil && !t.Guard(from, event, args) {
+		return false
+	}
+	return t.ContextGuard == nil || t.ContextGuard(ctx, from, event, args)
+}
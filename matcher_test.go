@@ -0,0 +1,104 @@
+package fsm
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// lastMatcher returns the last exact match instead of the first one.
+type lastMatcher struct{}
+
+func (lastMatcher) Match(transitions []Transition, from string, event string, args []interface{}) *Transition {
+	for i := len(transitions) - 1; i >= 0; i-- {
+		if transitions[i].From == from && transitions[i].Event == event {
+			return &transitions[i]
+		}
+	}
+	return nil
+}
+
+func TestWithMatcher(t *testing.T) {
+	transitions := []Transition{
+		{From: "Locked", Event: "Coin", To: "Unlocked"},
+		{From: "Locked", Event: "Coin", To: "Broken"},
+	}
+
+	fsm := NewStateMachine(nil, transitions...)
+	if trans, _ := fsm.FindTransition("Locked", "Coin"); trans.To != "Unlocked" {
+		t.Errorf("expected the first match by default, got %v", trans)
+	}
+
+	fsm.With(WithMatcher(lastMatcher{}))
+	if trans, _ := fsm.FindTransition("Locked", "Coin"); trans.To != "Broken" {
+		t.Errorf("expected the custom matcher to be used, got %v", trans)
+	}
+}
+
+// copyMatcher returns a copy of the first exact match instead of a pointer into the transitions.
+type copyMatcher struct{}
+
+func (copyMatcher) Match(transitions []Transition, from string, event string, args []interface{}) *Transition {
+	for _, t := range transitions {
+		if t.From == from && t.Event == event {
+			return &t
+		}
+	}
+	return nil
+}
+
+func TestWithMatcherCaseInsensitive(t *testing.T) {
+	fsm := NewStateMachine(nil,
+		Transition{From: "Locked", Event: "Coin", To: "Unlocked"},
+		Transition{From: "Unlocked", Event: "Push", To: "Locked"},
+	).With(WithMatcher(copyMatcher{}), WithCaseInsensitive(true))
+
+	trans, ok := fsm.FindTransition("unlocked", "PUSH")
+	if !ok || trans.From != "Unlocked" || trans.Event != "Push" || trans.To != "Locked" {
+		t.Errorf("expected the original transition for a copy of the match, got %v", trans)
+	}
+	if err := fsm.Trigger("LOCKED", "coin"); err != nil {
+		t.Errorf("trigger err: %v", err)
+	}
+}
+
+func TestWithParallelGuards(t *testing.T) {
+	slow := func(pass bool) ContextGuard {
+		return func(ctx context.Context, from string, event string, args []interface{}) bool {
+			time.Sleep(50 * time.Millisecond)
+			return pass
+		}
+	}
+	cancelled := make(chan struct{})
+	fsm := NewStateMachine(nil,
+		Transition{From: "Locked", Event: "Coin", To: "Rejected", ContextGuard: slow(false)},
+		Transition{From: "Locked", Event: "Coin", To: "Unlocked", ContextGuard: slow(true)},
+		Transition{From: "Locked", Event: "Coin", To: "Loser", ContextGuard: func(ctx context.Context, from string, event string, args []interface{}) bool {
+			<-ctx.Done()
+			close(cancelled)
+			return true
+		}},
+		Transition{From: "Locked", Event: Wildcard, To: "Fallback"},
+	).With(WithParallelGuards(true))
+
+	start := time.Now()
+	trans, ok := fsm.FindTransition("Locked", "Coin")
+	if !ok || trans.To != "Unlocked" {
+		t.Fatalf("expected the first passing transition, got %v", trans)
+	}
+	if elapsed := time.Since(start); elapsed >= 100*time.Millisecond {
+		t.Errorf("expected the guards to run concurrently, took %v", elapsed)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("expected the guard context of the losing transition to be cancelled")
+	}
+
+	if trans, _ := fsm.FindTransition("Locked", "Push"); trans.To != "Fallback" {
+		t.Errorf("expected the unguarded wildcard transition, got %v", trans)
+	}
+	if trans, _ := NewStateMachine(nil, Transition{From: "Locked", Event: "Coin", To: "Rejected", ContextGuard: slow(false)}).FindTransition("Locked", "Coin"); trans != nil {
+		t.Errorf("expected the context guard to reject sequentially, got %v", trans)
+	}
+}
@@ -0,0 +1,92 @@
+package fsm
+
+import (
+	"io"
+	"strings"
+)
+
+// StateEventMatrix is the state-event table of the transitions.
+type StateEventMatrix struct {
+	// States are the rows in definition order, Wildcard is the last row if a transition is from Wildcard.
+	States []string
+	// Events are the columns in definition order.
+	Events []string
+	// Cells holds the transitions of each state and event in definition order, Cells[i][j] are the transitions
+	// from States[i] for Events[j]. A cell has several transitions if they are guarded.
+	Cells [][][]Transition
+}
+
+// Matrix returns the state-event table of the transitions. Guards are not evaluated, and a transition from Wildcard
+// is only in the Wildcard row.
+func (m *StateMachine) Matrix() StateEventMatrix {
+	states := m.states()
+	var events []string
+	rows := make(map[string]int, len(states))
+	columns := make(map[string]int)
+	for i, s := range states {
+		rows[s] = i
+	}
+	for _, t := range m.transitions {
+		if _, ok := rows[t.From]; !ok {
+			rows[t.From] = len(states)
+			states = append(states, t.From)
+		}
+		if _, ok := columns[t.Event]; !ok {
+			columns[t.Event] = len(events)
+			events = append(events, t.Event)
+		}
+	}
+
+	cells := make([][][]Transition, len(states))
+	for i := range cells {
+		cells[i] = make([][]Transition, len(events))
+	}
+	for _, t := range m.transitions {
+		i, j := rows[t.From], columns[t.Event]
+		cells[i][j] = append(cells[i][j], t)
+	}
+	return StateEventMatrix{States: states, Events: events, Cells: cells}
+}
+
+// ExportMarkdownTable writes the state-event table of Matrix as a Markdown table, e.g. for READMEs. The rows are
+// states, the columns are events and a cell shows the target state and the action of the transition as
+// To(action), or - if there is no transition. Guarded transitions of a cell are separated by <br>.
+func (m *StateMachine) ExportMarkdownTable(w io.Writer) error {
+	matrix := m.Matrix()
+
+	var b strings.Builder
+	b.WriteString("| State |")
+	for _, e := range matrix.Events {
+		b.WriteString(" " + markdownEscape(e) + " |")
+	}
+	b.WriteString("\n|---|")
+	b.WriteString(strings.Repeat("---|", len(matrix.Events)))
+	b.WriteString("\n")
+
+	for i, s := range matrix.States {
+		b.WriteString("| " + markdownEscape(s) + " |")
+		for _, transitions := range matrix.Cells[i] {
+			cell := "-"
+			if len(transitions) > 0 {
+				targets := make([]string, len(transitions))
+				for k, t := range transitions {
+					targets[k] = markdownEscape(t.To)
+					if t.Action != "" {
+						targets[k] = targets[k] + "(" + markdownEscape(t.Action) + ")"
+					}
+				}
+				cell = strings.Join(targets, "<br>")
+			}
+			b.WriteString(" " + cell + " |")
+		}
+		b.WriteString("\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownEscape escapes the characters that break a Markdown table cell.
+func markdownEscape(s string) string {
+	return strings.NewReplacer(`|`, `\|`, "\n", " ").Replace(s)
+}
@@ -0,0 +1,38 @@
+package fsm
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMatrix(t *testing.T) {
+	fsm := NewStateMachine(nil,
+		Transition{From: "Locked", Event: "Coin", To: "Unlocked", Action: "check"},
+		Transition{From: Wildcard, Event: "Reset", To: "Locked"},
+	)
+
+	matrix := fsm.Matrix()
+	if got := fmt.Sprint(matrix.States, matrix.Events); got != "[Locked Unlocked *] [Coin Reset]" {
+		t.Errorf("unexpected rows and columns %s", got)
+	}
+	if len(matrix.Cells[0][0]) != 1 || len(matrix.Cells[0][1]) != 0 || len(matrix.Cells[2][1]) != 1 {
+		t.Errorf("unexpected cells %v", matrix.Cells)
+	}
+}
+
+func TestExportMarkdownTable(t *testing.T) {
+	var b strings.Builder
+	if err := initFSM().ExportMarkdownTable(&b); err != nil {
+		t.Fatalf("export err: %v", err)
+	}
+
+	want := `| State | Coin | Push |
+|---|---|---|
+| Locked | Unlocked(check) | Locked(invalid-push) |
+| Unlocked | Unlocked(repeat-check) | Locked(pass) |
+`
+	if b.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, b.String())
+	}
+}
@@ -0,0 +1,56 @@
+package fsm
+
+import "context"
+
+// MealyOutput computes the output of a Mealy machine for the current state and the input event.
+type MealyOutput func(from string, event string, args []interface{}) (output interface{}, err error)
+
+// MealyProcessor is an EventProcessor whose actions compute the output of a Mealy machine, use it with DefaultDelegate
+// and TriggerMealy. It doesn't handle OnExit and OnEnter, combine it with other processors by CompositeProcessor.
+// The output is only computed for transitions with an action.
+type MealyProcessor struct {
+	Output MealyOutput
+}
+
+// NewMealyProcessor creates a MealyProcessor.
+func NewMealyProcessor(output MealyOutput) *MealyProcessor {
+	return &MealyProcessor{Output: output}
+}
+
+// OnExit implements EventProcessor interface.
+func (p *MealyProcessor) OnExit(fromState string, args []interface{}) {}
+
+// Action implements EventProcessor interface, the output is dropped since there is no EventContext.
+func (p *MealyProcessor) Action(action string, fromState string, toState string, args []interface{}) error {
+	return nil
+}
+
+// ActionContext implements ContextProcessor interface and computes the output of the transition.
+func (p *MealyProcessor) ActionContext(c *EventContext) error {
+	output, err := p.Output(c.From, c.Event, c.Args)
+	if err != nil {
+		return err
+	}
+	c.output = output
+	return nil
+}
+
+// OnActionFailure implements EventProcessor interface.
+func (p *MealyProcessor) OnActionFailure(action string, fromState string, toState string, args []interface{}, err error) {
+}
+
+// OnEnter implements EventProcessor interface.
+func (p *MealyProcessor) OnEnter(toState string, args []interface{}) {}
+
+// TriggerMealy fires a event like Trigger and returns the output a MealyProcessor computed for the transition of
+// the event, and the state the object ends in. The output is nil if no MealyProcessor processed the transition.
+func (m *StateMachine) TriggerMealy(currentState string, event string, args ...interface{}) (interface{}, string, error) {
+	var output interface{}
+	first := true
+	state, err := m.trigger(currentState, event, args, m.observe(context.Background(), func(c *EventContext, err error) {
+		if first {
+			output, first = c.output, false
+		}
+	}))
+	return output, state, err
+}
@@ -0,0 +1,38 @@
+package fsm
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTriggerMealy(t *testing.T) {
+	// The machine tracks the parity of the bits it has read, the output is the parity after the input bit.
+	parity := NewMealyProcessor(func(from string, event string, args []interface{}) (interface{}, error) {
+		if (from == "Odd") != (event == "1") {
+			return 1, nil
+		}
+		return 0, nil
+	})
+	fsm := NewStateMachine(&DefaultDelegate{P: parity},
+		Transition{From: "Even", Event: "0", To: "Even", Action: "read"},
+		Transition{From: "Even", Event: "1", To: "Odd", Action: "read"},
+		Transition{From: "Odd", Event: "0", To: "Odd", Action: "read"},
+		Transition{From: "Odd", Event: "1", To: "Even", Action: "read"},
+	)
+
+	state := "Even"
+	var outputs []interface{}
+	for _, bit := range []string{"1", "0", "1", "1"} {
+		output, to, err := fsm.TriggerMealy(state, bit)
+		if err != nil {
+			t.Fatalf("trigger err: %v", err)
+		}
+		outputs, state = append(outputs, output), to
+	}
+	if got := fmt.Sprint(outputs); got != "[1 1 0 1]" {
+		t.Errorf("expected outputs [1 1 0 1], got %s", got)
+	}
+	if state != "Odd" {
+		t.Errorf("expected to end in Odd, got %s", state)
+	}
+}
@@ -0,0 +1,51 @@
+package fsm
+
+import "fmt"
+
+// Merge creates a state machine with the transitions of all machines, so modules defined separately can run as one.
+// Shared states connect the modules. Transitions that are defined more than once are kept once, and it returns an error
+// if two transitions have the same From, Event and GuardName but different To or Action.
+// Transitions with an unnamed guard can't be compared, so they are always kept.
+// Only transitions are merged: options and action params of the machines are not carried over,
+// configure the merged machine with With and RegisterActionParams. It returns an error if a machine is nil.
+func Merge(delegate Delegate, machines ...*StateMachine) (*StateMachine, error) {
+	lists := make([][]Transition, 0, len(machines))
+	for i, m := range machines {
+		if m == nil {
+			return nil, fmt.Errorf("state machine error: cannot merge nil machine at index %d", i)
+		}
+		lists = append(lists, m.transitions)
+	}
+
+	transitions, err := mergeTransitions(lists...)
+	if err != nil {
+		return nil, err
+	}
+	return NewStateMachine(delegate, transitions...), nil
+}
+
+// mergeTransitions concatenates the lists of transitions with the conflict detection of Merge.
+func mergeTransitions(lists ...[]Transition) ([]Transition, error) {
+	var transitions []Transition
+	seen := make(map[[3]string]Transition)
+
+	for _, list := range lists {
+		for _, t := range list {
+			if t.guarded() && t.GuardName == "" {
+				transitions = append(transitions, t)
+				continue
+			}
+			key := [3]string{t.From, t.Event, t.GuardName}
+			if old, ok := seen[key]; ok {
+				if old.To != t.To || old.Action != t.Action {
+					return nil, fmt.Errorf("state machine error: conflicting transitions %s and %s", old, t)
+				}
+				continue
+			}
+			seen[key] = t
+			transitions = append(transitions, t)
+		}
+	}
+
+	return transitions, nil
+}
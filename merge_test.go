@@ -0,0 +1,41 @@
+package fsm
+
+import "testing"
+
+func TestMerge(t *testing.T) {
+	auth := NewStateMachine(nil,
+		Transition{From: "Idle", Event: "Login", To: "Authed", Action: "login"},
+		Transition{From: "Authed", Event: "Logout", To: "Idle", Action: "logout"},
+	)
+	data := NewStateMachine(nil,
+		Transition{From: "Authed", Event: "Fetch", To: "Authed", Action: "fetch"},
+		Transition{From: "Authed", Event: "Logout", To: "Idle", Action: "logout"},
+	)
+
+	fsm, err := Merge(nil, auth, data)
+	if err != nil {
+		t.Fatalf("merge err: %v", err)
+	}
+	if len(fsm.transitions) != 3 {
+		t.Errorf("expected 3 transitions, got %v", fsm.transitions)
+	}
+	if fsm.findTransMatching("Authed", "Fetch", nil) == nil {
+		t.Error("expected the merged machine to handle Fetch from Authed")
+	}
+}
+
+func TestMergeConflict(t *testing.T) {
+	a := NewStateMachine(nil, Transition{From: "Idle", Event: "Go", To: "Running", Action: "start"})
+	b := NewStateMachine(nil, Transition{From: "Idle", Event: "Go", To: "Stopped", Action: "start"})
+
+	if _, err := Merge(nil, a, b); err == nil {
+		t.Error("expected a conflict error")
+	}
+}
+
+func TestMergeNil(t *testing.T) {
+	a := NewStateMachine(nil, Transition{From: "Idle", Event: "Go", To: "Running", Action: "start"})
+	if _, err := Merge(nil, a, nil); err == nil {
+		t.Error("expected an error for a nil machine")
+	}
+}
@@ -0,0 +1,31 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+)
+
+// StateAccessor reads the current state of a processing object.
+type StateAccessor func(obj interface{}) string
+
+// StateMutator writes the state a processing object entered.
+type StateMutator func(obj interface{}, to string)
+
+var errNoStateAccessor = errors.New("state machine error: TriggerObject needs WithStateAccessor")
+
+// TriggerObject fires a event for the object, the current state is read from the object with the StateAccessor
+// instead of being passed by the caller, so it can't drift from the real state. The object is passed to the delegate
+// as the only arg. When the object has entered a new state, it is written with the StateMutator, also if a follow-up
+// event failed after that. It returns an error if WithStateAccessor is not configured.
+func (m *StateMachine) TriggerObject(obj interface{}, event string) error {
+	if m.stateAccessor == nil || m.stateMutator == nil {
+		return errNoStateAccessor
+	}
+
+	from := m.stateAccessor(obj)
+	to, err := m.trigger(from, event, []interface{}{obj}, m.observe(context.Background(), nil))
+	if to != from {
+		m.stateMutator(obj, to)
+	}
+	return err
+}
@@ -0,0 +1,32 @@
+package fsm
+
+import "testing"
+
+func TestTriggerObject(t *testing.T) {
+	fsm := NewStateMachine(&DefaultDelegate{P: &recordingProcessor{}}, initFSM().transitions...)
+	if err := fsm.TriggerObject(&Turnstile{State: "Locked"}, "Coin"); err == nil {
+		t.Error("expected an error without a state accessor")
+	}
+
+	fsm.With(WithStateAccessor(
+		func(obj interface{}) string { return obj.(*Turnstile).State },
+		func(obj interface{}, to string) { obj.(*Turnstile).State = to },
+	))
+
+	ts := &Turnstile{ID: 1, State: "Locked"}
+	for _, event := range []string{"Push", "Coin", "Coin", "Push"} {
+		if err := fsm.TriggerObject(ts, event); err != nil {
+			t.Fatalf("trigger %s err: %v", event, err)
+		}
+	}
+	if ts.State != "Locked" {
+		t.Errorf("expected Locked, got %s", ts.State)
+	}
+
+	if err := fsm.TriggerObject(ts, "Coin"); err != nil || ts.State != "Unlocked" {
+		t.Errorf("expected Unlocked, got %s, %v", ts.State, err)
+	}
+	if err := fsm.TriggerObject(ts, "Kick"); err == nil || ts.State != "Unlocked" {
+		t.Errorf("expected the state to stay Unlocked on error, got %s, %v", ts.State, err)
+	}
+}
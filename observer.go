@@ -0,0 +1,56 @@
+package fsm
+
+import "context"
+
+// Observer is notified after each transition has been processed successfully, including follow-up events,
+// e.g. to log transitions or to collect metrics. to is the state the object entered.
+// Observers are notified after OnEnter and before the hook of WithAfterTransition.
+type Observer func(from string, event string, to string, action string, args []interface{})
+
+// ContextObserver is an Observer that also receives the context of TriggerContext or Run, e.g. to emit trace events
+// tied to the request. Other triggers pass context.Background().
+type ContextObserver func(ctx context.Context, from string, event string, to string, action string, args []interface{})
+
+// AddObserver adds an observer, observers are notified in the order they are added.
+// Like transitions, observers must be added before use.
+func (m *StateMachine) AddObserver(o Observer) {
+	m.AddContextObserver(func(ctx context.Context, from string, event string, to string, action string, args []interface{}) {
+		o(from, event, to, action, args)
+	})
+}
+
+// AddContextObserver adds an observer that receives the context, see AddObserver.
+func (m *StateMachine) AddContextObserver(o ContextObserver) {
+	m.observers = append(m.observers, o)
+}
+
+// TriggerContext fires a event like Trigger and passes ctx to the context observers.
+func (m *StateMachine) TriggerContext(ctx context.Context, currentState string, event string, args ...interface{}) error {
+	_, err := m.trigger(currentState, event, args, m.observe(ctx, nil))
+	return err
+}
+
+// TriggerWithDelegate fires a event like TriggerContext but handles it with d instead of the delegate of the state
+// machine, e.g. to serve tenants with different handlers from one machine definition without cloning it.
+func (m *StateMachine) TriggerWithDelegate(ctx context.Context, d Delegate, currentState string, event string, args ...interface{}) error {
+	_, err := m.triggerWith(d, currentState, event, args, m.observe(ctx, nil))
+	return err
+}
+
+// observe returns the observe callback of trigger that notifies the observers with ctx and then calls next,
+// it is nil if there is nothing to call.
+func (m *StateMachine) observe(ctx context.Context, next func(c *EventContext, err error)) func(c *EventContext, err error) {
+	if len(m.observers) == 0 {
+		return next
+	}
+	return func(c *EventContext, err error) {
+		if err == nil {
+			for _, o := range m.observers {
+				o(ctx, c.From, c.Event, c.To, c.Action, c.Args)
+			}
+		}
+		if next != nil {
+			next(c, err)
+		}
+	}
+}
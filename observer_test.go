@@ -0,0 +1,86 @@
+package fsm
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+type traceKey struct{}
+
+func TestObservers(t *testing.T) {
+	p := &recordingProcessor{emits: map[string][]string{"prepare": {"AutoStart"}}}
+	fsm := NewStateMachine(&DefaultDelegate{P: p},
+		Transition{From: "Idle", Event: "Start", To: "Ready", Action: "prepare"},
+		Transition{From: "Ready", Event: "AutoStart", To: "Running", Action: "run"},
+	)
+
+	var observed, traces []string
+	fsm.AddObserver(func(from, event, to, action string, args []interface{}) {
+		observed = append(observed, fmt.Sprintf("%s-%s->%s", from, event, to))
+	})
+	fsm.AddContextObserver(func(ctx context.Context, from, event, to, action string, args []interface{}) {
+		if id, ok := ctx.Value(traceKey{}).(string); ok {
+			traces = append(traces, id+":"+action)
+		}
+	})
+
+	ctx := context.WithValue(context.Background(), traceKey{}, "req-1")
+	if err := fsm.TriggerContext(ctx, "Idle", "Start"); err != nil {
+		t.Fatalf("trigger err: %v", err)
+	}
+	if got := fmt.Sprint(observed); got != "[Idle-Start->Ready Ready-AutoStart->Running]" {
+		t.Errorf("unexpected observed transitions %s", got)
+	}
+	if got := fmt.Sprint(traces); got != "[req-1:prepare req-1:run]" {
+		t.Errorf("expected the context to reach the observer, got %s", got)
+	}
+
+	if err := fsm.Trigger("Idle", "Kick"); err == nil || len(observed) != 2 {
+		t.Errorf("expected failed triggers not to be observed, got %v, %v", observed, err)
+	}
+}
+
+func TestTransitionOrder(t *testing.T) {
+	p := &recordingProcessor{emits: map[string][]string{"check": {"Auto"}}}
+	fsm := NewStateMachine(&DefaultDelegate{P: p},
+		Transition{From: "Locked", Event: "Coin", To: "Unlocked", Action: "check"},
+		Transition{From: "Unlocked", Event: "Auto", To: "Open", Action: "open"},
+	).With(WithAfterTransition(func(from string, event string, to string, action string, args []interface{}) {
+		p.calls = append(p.calls, "after:"+event)
+	}))
+	fsm.AddObserver(func(from string, event string, to string, action string, args []interface{}) {
+		p.calls = append(p.calls, "observer:"+event)
+	})
+
+	if err := fsm.Trigger("Locked", "Coin"); err != nil {
+		t.Fatalf("trigger err: %v", err)
+	}
+	want := "[exit:Locked action:check enter:Unlocked observer:Coin after:Coin exit:Unlocked action:open enter:Open observer:Auto after:Auto]"
+	if got := fmt.Sprint(p.calls); got != want {
+		t.Errorf("expected calls %s, got %s", want, got)
+	}
+}
+
+func TestTriggerWithDelegate(t *testing.T) {
+	fsm := NewStateMachine(nil,
+		Transition{From: "Idle", Event: "Start", To: "Ready", Action: "prepare"},
+		Transition{From: "Ready", Event: "Run", To: "Running", Action: "run"},
+	)
+
+	tenantA, tenantB := &recordingProcessor{}, &recordingProcessor{}
+	ctx := context.Background()
+	if err := fsm.TriggerWithDelegate(ctx, &DefaultDelegate{P: tenantA}, "Idle", "Start"); err != nil {
+		t.Fatalf("trigger err: %v", err)
+	}
+	if err := fsm.TriggerWithDelegate(ctx, &DefaultDelegate{P: tenantB}, "Ready", "Run"); err != nil {
+		t.Fatalf("trigger err: %v", err)
+	}
+	if got := fmt.Sprint(tenantA.calls, tenantB.calls); got != "[exit:Idle action:prepare enter:Ready] [exit:Ready action:run enter:Running]" {
+		t.Errorf("expected each tenant to handle its own event, got %s", got)
+	}
+
+	if err := fsm.Trigger("Idle", "Start"); err != ErrNoDelegate {
+		t.Errorf("expected the machine delegate to stay unset, got %v", err)
+	}
+}
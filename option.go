@@ -0,0 +1,237 @@
+package fsm
+
+import (
+	"strings"
+	"time"
+)
+
+// OptionFn configures a StateMachine.
+type OptionFn func(*StateMachine)
+
+// With applies options to the state machine and returns it. Like transitions, options must be configured before use.
+func (m *StateMachine) With(options ...OptionFn) *StateMachine {
+	for _, opt := range options {
+		opt(m)
+	}
+	return m
+}
+
+// WithClock sets the clock used by duration-based behavior. RealClock is used by default.
+func WithClock(clock Clock) OptionFn {
+	return func(m *StateMachine) {
+		m.clock = clock
+	}
+}
+
+// WithActionDescriptions sets descriptions of actions. WriteDot renders them as a legend of the diagram.
+func WithActionDescriptions(descriptions map[string]string) OptionFn {
+	return func(m *StateMachine) {
+		m.actionDescriptions = descriptions
+	}
+}
+
+// WithFirstSeen sets a callback that is fired the first time any object enters a state during the lifetime of the
+// state machine. It is fired once per distinct state and is safe for concurrent triggers.
+func WithFirstSeen(fn func(state string)) OptionFn {
+	return func(m *StateMachine) {
+		m.firstSeen = fn
+	}
+}
+
+// WithDeprecationHandler sets a callback that is called before a deprecated transition is processed, e.g. to log a warning.
+func WithDeprecationHandler(fn func(t Transition)) OptionFn {
+	return func(m *StateMachine) {
+		m.deprecationHandler = fn
+	}
+}
+
+// WithMatcher sets the Matcher that finds the transition for an event. LinearMatcher is used by default.
+func WithMatcher(matcher Matcher) OptionFn {
+	return func(m *StateMachine) {
+		m.matcher = matcher
+	}
+}
+
+// WithParallelGuards makes the state machine evaluate the guards of the matching transitions concurrently with
+// ParallelMatcher, or sequentially with LinearMatcher when it is false. It replaces the matcher of WithMatcher.
+func WithParallelGuards(parallel bool) OptionFn {
+	return func(m *StateMachine) {
+		if parallel {
+			m.matcher = ParallelMatcher{}
+		} else {
+			m.matcher = LinearMatcher{}
+		}
+	}
+}
+
+// WithStateGroups groups states in the diagram, each group name maps to its states. WriteDot renders a group as
+// a cluster with a border labeled by the group name. States that are not grouped are rendered normally.
+func WithStateGroups(groups map[string][]string) OptionFn {
+	return func(m *StateMachine) {
+		m.stateGroups = groups
+	}
+}
+
+// WithMultilineLabels renders the event and the action of edges on separate lines instead of "Event | Action",
+// which is more readable for dense diagrams.
+func WithMultilineLabels(multiline bool) OptionFn {
+	return func(m *StateMachine) {
+		m.multilineLabels = multiline
+	}
+}
+
+// WithActiveTags enables only the transitions with one of the tags, see Transition.Tags, e.g. to switch groups of
+// transitions by feature flags. Untagged transitions are always enabled. No tags enable all transitions.
+func WithActiveTags(tags ...string) OptionFn {
+	return func(m *StateMachine) {
+		m.activeTags = make(map[string]bool, len(tags))
+		for _, tag := range tags {
+			m.activeTags[tag] = true
+		}
+	}
+}
+
+// WithEventNormalizer sets a function that normalizes events before matching, e.g. to lowercase and trim events of
+// external systems. The events of the transitions are normalized when the option is applied, so configure it after
+// the transitions. The delegate still receives the original event in EventContext.Event.
+func WithEventNormalizer(fn func(event string) string) OptionFn {
+	return func(m *StateMachine) {
+		m.eventNormalizer = fn
+		for i := range m.transitions {
+			m.transitions[i].Event = m.normalizeEvent(m.transitions[i].Event)
+		}
+	}
+}
+
+// WithFinalStates declares the final states, objects are expected to stay in them. See DeadEnds.
+func WithFinalStates(states ...string) OptionFn {
+	return func(m *StateMachine) {
+		m.finalStates = make(map[string]bool, len(states))
+		for _, s := range states {
+			m.finalStates[s] = true
+		}
+	}
+}
+
+// WithIgnoredEvents makes the events no-ops in the state when no transition matches them: Trigger returns nil
+// without calling the delegate and the object stays in the state, instead of failing with ErrNoTransition.
+// It is cleaner than self-transitions without action, e.g. for quiescent states. Applying it again for the state
+// adds the events. The events are matched like the events of transitions, so configure it after WithEventNormalizer
+// and WithCaseInsensitive.
+func WithIgnoredEvents(state string, events ...string) OptionFn {
+	return func(m *StateMachine) {
+		if m.ignoredEvents == nil {
+			m.ignoredEvents = make(map[string]map[string]bool)
+		}
+		for _, e := range events {
+			s, e := m.ignoredKey(state, e)
+			if m.ignoredEvents[s] == nil {
+				m.ignoredEvents[s] = make(map[string]bool)
+			}
+			m.ignoredEvents[s][e] = true
+		}
+	}
+}
+
+// WithFinalPredicate sets a predicate deciding at runtime whether a state is final for the processing object carried
+// by args, in addition to the states declared with WithFinalStates. See IsFinalFor.
+func WithFinalPredicate(fn func(state string, args []interface{}) bool) OptionFn {
+	return func(m *StateMachine) {
+		m.finalPredicate = fn
+	}
+}
+
+// WithStateParents sets the parent of nested states, each child state maps to its composite parent state.
+// WriteDot renders a composite state as a cluster labeled by its name that contains its children, nesting clusters
+// for deeper levels. Don't put nested states in WithStateGroups, a node can only be drawn in one cluster.
+func WithStateParents(parents map[string]string) OptionFn {
+	return func(m *StateMachine) {
+		m.stateParents = parents
+	}
+}
+
+// WithActionTimer sets a callback that DefaultDelegate calls with the duration of each action of the processor,
+// e.g. to feed latency histograms. OnExit and OnEnter are not measured. The duration is measured with the clock
+// of the state machine, see WithClock.
+func WithActionTimer(fn func(action string, d time.Duration)) OptionFn {
+	return func(m *StateMachine) {
+		m.actionTimer = fn
+	}
+}
+
+// WithStateAccessor sets how TriggerObject reads and writes the state of the processing objects.
+func WithStateAccessor(get StateAccessor, set StateMutator) OptionFn {
+	return func(m *StateMachine) {
+		m.stateAccessor = get
+		m.stateMutator = set
+	}
+}
+
+// WithArgsTransformer sets a function that transforms the args of each transition before the delegate handles it,
+// e.g. to inject a logger or a correlation ID into every transition. Guards are evaluated with the args of Trigger,
+// the delegate, observers and follow-up events receive the transformed args. It is called again for each follow-up
+// event, with the args of the previous transition.
+func WithArgsTransformer(fn func(from, event, to string, args []interface{}) []interface{}) OptionFn {
+	return func(m *StateMachine) {
+		m.argsTransformer = fn
+	}
+}
+
+// WithContinueOnActionError makes DefaultDelegate run all actions of a transition, the exit action of From, the
+// transition action and the entry action of To, even if one of them fails, e.g. for best-effort notifications.
+// Trigger returns ActionErrors listing each failure and the object stays in From. By default the first failing
+// action stops the transition.
+func WithContinueOnActionError(continueOnError bool) OptionFn {
+	return func(m *StateMachine) {
+		m.continueOnError = continueOnError
+	}
+}
+
+// WithCaseInsensitive makes the state machine match the states and events of transitions case-insensitively,
+// e.g. "coin" matches a transition of "Coin". Lowercase copies of the transitions are made when the option is
+// applied, so configure it after the transitions and WithEventNormalizer. Guards receive the lowercase state and
+// event, the delegate receives them as triggered.
+func WithCaseInsensitive(caseInsensitive bool) OptionFn {
+	return func(m *StateMachine) {
+		m.folded = nil
+		if !caseInsensitive {
+			return
+		}
+		m.folded = make([]Transition, len(m.transitions))
+		for i, t := range m.transitions {
+			t.From, t.Event, t.index = strings.ToLower(t.From), strings.ToLower(t.Event), i
+			m.folded[i] = t
+		}
+	}
+}
+
+// WithVersion sets the version of the transition table, e.g. for migrations and audits. ExportJSON and MarshalBinary
+// include it, see CompatibleWith.
+func WithVersion(version string) OptionFn {
+	return func(m *StateMachine) {
+		m.version = version
+	}
+}
+
+// WithAfterTransition sets a hook that is called after each successful transition, including follow-up events.
+// Within a transition the order is: OnExit, the action, OnEnter, the observers, then the hook, so the hook sees
+// what OnEnter persisted and what the observers recorded. Follow-up events are processed after the hook.
+func WithAfterTransition(fn Observer) OptionFn {
+	return func(m *StateMachine) {
+		m.afterTransition = fn
+	}
+}
+
+// WithStats enables the hit counters of the transitions, see HitCounts.
+func WithStats(enabled bool) OptionFn {
+	return func(m *StateMachine) {
+		m.stats = enabled
+	}
+}
+
+// WithSeenStore sets the store of processed event IDs for TriggerIdempotent.
+func WithSeenStore(store SeenStore) OptionFn {
+	return func(m *StateMachine) {
+		m.seenStore = store
+	}
+}
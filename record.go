@@ -0,0 +1,30 @@
+package fsm
+
+import "context"
+
+// Effect is a transition processed by TriggerRecorded with the side effects that ran, so the caller can compensate it.
+type Effect struct {
+	Event  string
+	From   string
+	To     string
+	Action string
+	// Exited and Entered report whether OnExit of From and OnEnter of To ran. They are only reported by DefaultDelegate,
+	// other delegates report false.
+	Exited  bool
+	Entered bool
+	// Err is the error of the action, To is then the declared state which was not entered.
+	Err error
+}
+
+// TriggerRecorded fires a event like Trigger and returns the effects of the transitions in the order they ran,
+// including the follow-up events and the transition whose action failed. An event without a matching transition
+// has no effects. The effects drive Saga-style compensation: on a later failure, the caller undoes the effects
+// in reverse order, e.g. by firing compensating events.
+func (m *StateMachine) TriggerRecorded(currentState string, event string, args ...interface{}) ([]Effect, error) {
+	var effects []Effect
+	_, err := m.trigger(currentState, event, args, m.observe(context.Background(), func(c *EventContext, err error) {
+		effects = append(effects, Effect{Event: c.Event, From: c.From, To: c.To, Action: c.Action,
+			Exited: c.exited, Entered: c.entered, Err: err})
+	}))
+	return effects, err
+}
@@ -0,0 +1,141 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// compensationProcessor records calls and fails the actions in fail.
+type compensationProcessor struct {
+	recordingProcessor
+	fail map[string]bool
+}
+
+func (p *compensationProcessor) ActionContext(c *EventContext) error {
+	p.recordingProcessor.ActionContext(c)
+	if p.fail[c.Action] {
+		return errors.New("failed " + c.Action)
+	}
+	return nil
+}
+
+func TestTriggerRecorded(t *testing.T) {
+	p := &compensationProcessor{
+		recordingProcessor: recordingProcessor{emits: map[string][]string{"reserve": {"Charge"}}},
+		fail:               map[string]bool{"charge": true},
+	}
+	fsm := NewStateMachine(&DefaultDelegate{P: p},
+		Transition{From: "New", Event: "Order", To: "Reserved", Action: "reserve"},
+		Transition{From: "Reserved", Event: "Charge", To: "Paid", Action: "charge"},
+	)
+
+	effects, err := fsm.TriggerRecorded("New", "Order")
+	if err == nil {
+		t.Fatal("expected the charge to fail")
+	}
+	if len(effects) != 2 {
+		t.Fatalf("expected 2 effects, got %+v", effects)
+	}
+
+	reserve, charge := effects[0], effects[1]
+	if reserve.From != "New" || reserve.To != "Reserved" || reserve.Action != "reserve" ||
+		!reserve.Exited || !reserve.Entered || reserve.Err != nil {
+		t.Errorf("unexpected reserve effect %+v", reserve)
+	}
+	if charge.From != "Reserved" || charge.To != "Paid" || !charge.Exited || charge.Entered || charge.Err == nil {
+		t.Errorf("unexpected charge effect %+v", charge)
+	}
+}
+
+func TestTriggerRecordedNoTransition(t *testing.T) {
+	fsm := initFSM()
+	effects, err := fsm.TriggerRecorded("Locked", "Kick")
+	if !errors.Is(err, ErrNoTransition) || len(effects) != 0 {
+		t.Errorf("expected no effects and ErrNoTransition, got %+v, %v", effects, err)
+	}
+}
+
+func TestOnFailureCompensation(t *testing.T) {
+	p := &compensationProcessor{fail: map[string]bool{"charge": true}}
+	fsm := NewStateMachine(&DefaultDelegate{P: p},
+		Transition{From: "Reserved", Event: "Charge", To: "Paid", Action: "charge", OnFailure: "Refunding"},
+		Transition{From: "Reserved", Event: "Cancel", To: "Cancelled", Action: "release", OnFailure: "Failed"},
+	)
+
+	effects, err := fsm.TriggerRecorded("Reserved", "Charge")
+	var compensated *CompensationError
+	if !errors.As(err, &compensated) || compensated.State != "Refunding" || compensated.Action != "charge" {
+		t.Fatalf("expected a compensation error, got %v", err)
+	}
+	if len(effects) != 1 || effects[0].To != "Refunding" || !effects[0].Entered {
+		t.Errorf("expected the compensation state to be entered, got %+v", effects)
+	}
+	want := "[exit:Reserved action:charge failure:charge enter:Refunding]"
+	if got := fmt.Sprint(p.calls); got != want {
+		t.Errorf("expected calls %s, got %s", want, got)
+	}
+
+	if err := fsm.Trigger("Reserved", "Cancel"); err != nil {
+		t.Errorf("expected a successful action not to compensate, got %v", err)
+	}
+}
+
+func TestOnFailureCompensationDelegate(t *testing.T) {
+	var calls []string
+	fsm := NewStateMachine(delegateFunc(func(action string, fromState string, toState string, args []interface{}) error {
+		calls = append(calls, action+":"+toState)
+		if action == "charge" {
+			return errors.New("declined")
+		}
+		return nil
+	}), Transition{From: "Reserved", Event: "Charge", To: "Paid", Action: "charge", OnFailure: "Refunding"})
+
+	ts := &Turnstile{State: "Reserved"}
+	err := fsm.With(WithStateAccessor(func(obj interface{}) string {
+		return obj.(*Turnstile).State
+	}, func(obj interface{}, to string) {
+		obj.(*Turnstile).State = to
+	})).TriggerObject(ts, "Charge")
+	var compensated *CompensationError
+	if !errors.As(err, &compensated) || ts.State != "Refunding" {
+		t.Fatalf("expected the object to enter Refunding, got %v in %s", err, ts.State)
+	}
+	if got := fmt.Sprint(calls); got != "[charge:Paid :Refunding]" {
+		t.Errorf("unexpected delegate calls %s", got)
+	}
+}
+
+// delegateFunc is a Delegate function.
+type delegateFunc func(action string, fromState string, toState string, args []interface{}) error
+
+func (f delegateFunc) HandleEvent(action string, fromState string, toState string, args []interface{}) error {
+	return f(action, fromState, toState, args)
+}
+
+func TestOnFailureCompensationContinueOnActionError(t *testing.T) {
+	p := &compensationProcessor{fail: map[string]bool{"x": true}}
+	fsm := NewStateMachine(&DefaultDelegate{P: p},
+		Transition{From: "A", Event: "Go", To: "B", Action: "x", OnFailure: "Broken"},
+	).With(WithContinueOnActionError(true), WithStateAccessor(func(obj interface{}) string {
+		return obj.(*Turnstile).State
+	}, func(obj interface{}, to string) {
+		obj.(*Turnstile).State = to
+	}))
+	fsm.StateEntry("B", "log")
+
+	ts := &Turnstile{State: "A"}
+	err := fsm.TriggerObject(ts, "Go")
+	var compensated *CompensationError
+	if !errors.As(err, &compensated) || ts.State != "Broken" {
+		t.Fatalf("expected the object to enter Broken, got %v in %s", err, ts.State)
+	}
+	var errs ActionErrors
+	if !errors.As(err, &errs) || len(errs) != 1 {
+		t.Errorf("expected the action errors to be wrapped, got %v", err)
+	}
+	want := "[exit:A action:x failure:x action:log enter:Broken]"
+	if got := fmt.Sprint(p.calls); got != want {
+		t.Errorf("expected calls %s, got %s", want, got)
+	}
+}
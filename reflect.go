@@ -0,0 +1,172 @@
+package fsm
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// actionMethod is the signature of action methods dispatched by ReflectProcessor.
+type actionMethod = func(fromState string, toState string, args []interface{}) error
+
+// ReflectProcessor is an EventProcessor that dispatches actions to methods of a user value instead of a switch in Action.
+// Action "check" is dispatched to the method ActionCheck, and "repeat-check" to ActionRepeatCheck: the action is split
+// at characters that are not letters or digits and each part is title-cased. The methods must have the signature
+//
+//	func(fromState string, toState string, args []interface{}) error
+//
+// OnExit, OnEnter and OnActionFailure are forwarded to the value if it has these methods of EventProcessor.
+type ReflectProcessor struct {
+	target  interface{}
+	methods map[string]actionMethod
+}
+
+// NewReflectProcessor creates a ReflectProcessor for the methods of target. The actions of transitions are validated,
+// it returns an error if an action has no corresponding method.
+func NewReflectProcessor(target interface{}, transitions ...Transition) (*ReflectProcessor, error) {
+	p := &ReflectProcessor{target: target, methods: make(map[string]actionMethod)}
+
+	v := reflect.ValueOf(target)
+	for i := 0; i < v.NumMethod(); i++ {
+		name := v.Type().Method(i).Name
+		if !strings.HasPrefix(name, "Action") {
+			continue
+		}
+		if fn, ok := v.Method(i).Interface().(actionMethod); ok {
+			p.methods[name] = fn
+		}
+	}
+
+	for _, t := range transitions {
+		if t.Action == "" {
+			continue
+		}
+		if _, ok := p.methods[ActionMethodName(t.Action)]; !ok {
+			return nil, fmt.Errorf("state machine error: %T has no method %s for action [%s] of %s", target, ActionMethodName(t.Action), t.Action, t)
+		}
+	}
+	return p, nil
+}
+
+// ActionMethodName returns the name of the method ReflectProcessor dispatches the action to.
+func ActionMethodName(action string) string {
+	parts := strings.FieldsFunc(action, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	name := "Action"
+	for _, part := range parts {
+		runes := []rune(part)
+		runes[0] = unicode.ToUpper(runes[0])
+		name = name + string(runes)
+	}
+	return name
+}
+
+// OnExit implements EventProcessor interface.
+func (p *ReflectProcessor) OnExit(fromState string, args []interface{}) {
+	if h, ok := p.target.(interface {
+		OnExit(fromState string, args []interface{})
+	}); ok {
+		h.OnExit(fromState, args)
+	}
+}
+
+// Action implements EventProcessor interface and calls the method of the action.
+func (p *ReflectProcessor) Action(action string, fromState string, toState string, args []interface{}) error {
+	fn, ok := p.methods[ActionMethodName(action)]
+	if !ok {
+		return fmt.Errorf("%w: %T has no method %s for action [%s]", ErrActionNotFound, p.target, ActionMethodName(action), action)
+	}
+	return fn(fromState, toState, args)
+}
+
+// OnActionFailure implements EventProcessor interface.
+func (p *ReflectProcessor) OnActionFailure(action string, fromState string, toState string, args []interface{}, err error) {
+	if h, ok := p.target.(interface {
+		OnActionFailure(action string, fromState string, toState string, args []interface{}, err error)
+	}); ok {
+		h.OnActionFailure(action, fromState, toState, args, err)
+	}
+}
+
+// OnEnter implements EventProcessor interface.
+func (p *ReflectProcessor) OnEnter(toState string, args []interface{}) {
+	if h, ok := p.target.(interface {
+		OnEnter(toState string, args []interface{})
+	}); ok {
+		h.OnEnter(toState, args)
+	}
+}
+
+// FromStruct reads transitions from the `fsm` tags of the fields of a struct, or a pointer to a struct, e.g.
+//
+//	type Turnstile struct {
+//		Coin struct{} `fsm:"from=Locked,event=Coin,to=Unlocked,action=check"`
+//		Push struct{} `fsm:"from=Unlocked,event=Push,to=Locked"`
+//	}
+//
+// A tag is a comma separated list of key=value pairs, the keys are from, event, to, action, guard, condition,
+// onGuardFail and onFailure, like the JSON names of Transition. from, event and to are required. Fields without a tag or with the
+// tag "-" are skipped. The transitions are in the order of the fields.
+func FromStruct(v interface{}) ([]Transition, error) {
+	rt := reflect.TypeOf(v)
+	if rt != nil && rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if rt == nil || rt.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("state machine error: FromStruct needs a struct, got %T", v)
+	}
+
+	var transitions []Transition
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		tag, ok := f.Tag.Lookup("fsm")
+		if !ok || tag == "-" {
+			continue
+		}
+		t, err := parseTransitionTag(tag)
+		if err != nil {
+			return nil, fmt.Errorf("state machine error: malformed fsm tag of field %s.%s: %v", rt.Name(), f.Name, err)
+		}
+		transitions = append(transitions, t)
+	}
+	return transitions, nil
+}
+
+// parseTransitionTag parses an `fsm` tag of FromStruct.
+func parseTransitionTag(tag string) (Transition, error) {
+	var t Transition
+	fields := map[string]*string{
+		"from":        &t.From,
+		"event":       &t.Event,
+		"to":          &t.To,
+		"action":      &t.Action,
+		"guard":       &t.GuardName,
+		"condition":   &t.Condition,
+		"onGuardFail": &t.OnGuardFail,
+		"onFailure":   &t.OnFailure,
+	}
+	for _, pair := range strings.Split(tag, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		key := strings.TrimSpace(kv[0])
+		if len(kv) != 2 {
+			return t, fmt.Errorf("%q is not a key=value pair", pair)
+		}
+		field, ok := fields[key]
+		if !ok {
+			return t, fmt.Errorf("unknown key %q", key)
+		}
+		if *field != "" {
+			return t, fmt.Errorf("duplicate key %q", key)
+		}
+		*field = strings.TrimSpace(kv[1])
+	}
+	for _, key := range []string{"from", "event", "to"} {
+		if *fields[key] == "" {
+			return t, fmt.Errorf("missing %s", key)
+		}
+	}
+	return t, nil
+}
@@ -0,0 +1,125 @@
+package fsm
+
+import (
+	"errors"
+	"testing"
+)
+
+// turnstileActions implements the turnstile actions as methods.
+type turnstileActions struct{}
+
+func (a *turnstileActions) ActionCheck(fromState string, toState string, args []interface{}) error {
+	t := args[0].(*Turnstile)
+	t.EventCount++
+	t.CoinCount++
+	return nil
+}
+
+func (a *turnstileActions) ActionRepeatCheck(fromState string, toState string, args []interface{}) error {
+	args[0].(*Turnstile).EventCount++
+	return errors.New("转门暂时故障")
+}
+
+func (a *turnstileActions) ActionInvalidPush(fromState string, toState string, args []interface{}) error {
+	args[0].(*Turnstile).EventCount++
+	return nil
+}
+
+func (a *turnstileActions) ActionPass(fromState string, toState string, args []interface{}) error {
+	t := args[0].(*Turnstile)
+	t.EventCount++
+	t.PassCount++
+	return nil
+}
+
+func (a *turnstileActions) OnEnter(toState string, args []interface{}) {
+	t := args[0].(*Turnstile)
+	t.State = toState
+	t.States = append(t.States, toState)
+}
+
+func TestReflectProcessor(t *testing.T) {
+	transitions := initFSM().transitions
+	p, err := NewReflectProcessor(&turnstileActions{}, transitions...)
+	if err != nil {
+		t.Fatalf("new processor err: %v", err)
+	}
+	fsm := NewStateMachine(&DefaultDelegate{P: p}, transitions...)
+
+	ts := &Turnstile{ID: 1, State: "Locked", States: []string{"Locked"}}
+	for _, event := range []string{"Push", "Push", "Coin", "Coin", "Push", "Push"} {
+		fsm.Trigger(ts.State, event, ts)
+	}
+
+	lastState := Turnstile{
+		ID:         1,
+		EventCount: 6,
+		CoinCount:  1,
+		PassCount:  1,
+		State:      "Locked",
+		States:     []string{"Locked", "Unlocked", "Locked"},
+	}
+	if !compareTurnstile(&lastState, ts) {
+		t.Errorf("Expected last state: %+v, but got %+v", lastState, ts)
+	}
+}
+
+func TestReflectProcessorMissingMethod(t *testing.T) {
+	_, err := NewReflectProcessor(&turnstileActions{}, Transition{From: "Locked", Event: "Kick", To: "Broken", Action: "break-down"})
+	if err == nil {
+		t.Fatal("expected an error for a missing method")
+	}
+	if ActionMethodName("break-down") != "ActionBreakDown" {
+		t.Errorf("unexpected method name %s", ActionMethodName("break-down"))
+	}
+}
+
+// turnstileStates defines the turnstile transitions with struct tags.
+type turnstileStates struct {
+	Coin        struct{} `fsm:"from=Locked,event=Coin,to=Unlocked,action=check"`
+	InvalidPush struct{} `fsm:"from=Locked, event=Push, to=Locked, action=invalid-push"`
+	Pass        struct{} `fsm:"from=Unlocked,event=Push,to=Locked,action=pass"`
+	RepeatCheck struct{} `fsm:"from=Unlocked,event=Coin,to=Unlocked,action=repeat-check"`
+	Name        string
+	Skipped     struct{} `fsm:"-"`
+}
+
+func TestFromStruct(t *testing.T) {
+	transitions, err := FromStruct(&turnstileStates{})
+	if err != nil {
+		t.Fatalf("FromStruct err: %v", err)
+	}
+	fsm := NewStateMachine(&DefaultDelegate{P: &TurnstileEventProcessor{}}, transitions...)
+	if diff := Diff(initFSM(), fsm); !diff.Empty() {
+		t.Errorf("unexpected transitions: %s", diff)
+	}
+
+	ts := &Turnstile{State: "Locked"}
+	if err := fsm.Trigger(ts.State, "Coin", ts); err != nil {
+		t.Fatalf("trigger err: %v", err)
+	}
+}
+
+func TestFromStructMalformed(t *testing.T) {
+	tests := []interface{}{
+		struct {
+			A struct{} `fsm:"from=Locked,event=Coin"`
+		}{},
+		struct {
+			A struct{} `fsm:"from=Locked,event=Coin,to=Unlocked,when=now"`
+		}{},
+		struct {
+			A struct{} `fsm:"from=Locked,event,to=Unlocked"`
+		}{},
+		struct {
+			A struct{} `fsm:"from=Locked,from=Unlocked,event=Coin,to=Unlocked"`
+		}{},
+		"Locked",
+		nil,
+	}
+	for _, v := range tests {
+		if _, err := FromStruct(v); err == nil {
+			t.Errorf("expected an error for %#v", v)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package fsm
+
+import (
+	"sort"
+	"sync"
+)
+
+var registry = struct {
+	sync.RWMutex
+	machines map[string]*StateMachine
+}{machines: make(map[string]*StateMachine)}
+
+// Register registers the state machine by name, so modules can look it up with Get. A machine registered
+// with the same name is replaced. It is safe for concurrent use.
+func Register(name string, m *StateMachine) {
+	registry.Lock()
+	registry.machines[name] = m
+	registry.Unlock()
+}
+
+// Get returns the state machine registered by name.
+func Get(name string) (*StateMachine, bool) {
+	registry.RLock()
+	m, ok := registry.machines[name]
+	registry.RUnlock()
+	return m, ok
+}
+
+// Names returns the sorted names of the registered state machines.
+func Names() []string {
+	registry.RLock()
+	names := make([]string, 0, len(registry.machines))
+	for name := range registry.machines {
+		names = append(names, name)
+	}
+	registry.RUnlock()
+	sort.Strings(names)
+	return names
+}
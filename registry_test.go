@@ -0,0 +1,35 @@
+package fsm
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	turnstile := initFSM()
+	Register("test-turnstile", turnstile)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			Register(fmt.Sprintf("test-machine-%d", i), NewStateMachine(nil))
+			Get("test-turnstile")
+		}(i)
+	}
+	wg.Wait()
+
+	if m, ok := Get("test-turnstile"); !ok || m != turnstile {
+		t.Errorf("expected the registered turnstile, got %v, %v", m, ok)
+	}
+	if _, ok := Get("test-missing"); ok {
+		t.Error("expected no machine for an unknown name")
+	}
+
+	names := Names()
+	if len(names) < 11 || names[0] != "test-machine-0" {
+		t.Errorf("expected sorted names, got %v", names)
+	}
+}
@@ -0,0 +1,111 @@
+package fsm
+
+import (
+	"container/heap"
+	"context"
+)
+
+// Run processes the events of one object in the order they arrive, starting from currentState.
+// It is RunPriority without priorities.
+func (m *StateMachine) Run(ctx context.Context, currentState string, events <-chan string, args ...interface{}) (string, error) {
+	return m.RunPriority(ctx, currentState, events, nil, args...)
+}
+
+// RunPriority processes the events of one object, starting from currentState, until events is closed or ctx is done.
+// When several events are pending, the one with the highest priority returned by priority is processed first,
+// events with the same priority are processed in arrival order. A nil priority treats all events equally.
+// Each event runs to completion before the next one is picked, a transition is never preempted.
+// At most maxPendingEvents events are queued, further events wait in the channel.
+// It returns the state the object ends in, and stops at the first error of a transition.
+func (m *StateMachine) RunPriority(ctx context.Context, currentState string, events <-chan string, priority func(event string) int, args ...interface{}) (string, error) {
+	if priority == nil {
+		priority = func(string) int { return 0 }
+	}
+
+	state := currentState
+	var queue eventQueue
+	var seq uint64
+	push := func(event string) {
+		heap.Push(&queue, queuedEvent{event: event, priority: priority(event), seq: seq})
+		seq++
+	}
+
+	for {
+		if queue.Len() == 0 {
+			select {
+			case <-ctx.Done():
+				return state, ctx.Err()
+			case event, ok := <-events:
+				if !ok {
+					return state, nil
+				}
+				push(event)
+			}
+		}
+
+		// collect the events that are already pending so priorities apply to them, the queue is bounded so a fast
+		// producer can neither starve processing nor grow memory without limit
+		for drained := false; !drained && queue.Len() < maxPendingEvents; {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					events = nil
+					drained = true
+					break
+				}
+				push(event)
+			default:
+				drained = true
+			}
+		}
+
+		if err := ctx.Err(); err != nil {
+			return state, err
+		}
+
+		next := heap.Pop(&queue).(queuedEvent)
+		var err error
+		state, err = m.trigger(state, next.event, args, m.observe(ctx, nil))
+		if err != nil {
+			return state, err
+		}
+
+		if events == nil && queue.Len() == 0 {
+			return state, nil
+		}
+	}
+}
+
+// maxPendingEvents is the maximum number of events RunPriority takes from the channel before processing one.
+// Further events wait in the channel, so priorities only apply among the queued events.
+const maxPendingEvents = 64
+
+type queuedEvent struct {
+	event    string
+	priority int
+	seq      uint64
+}
+
+// eventQueue is a heap of pending events ordered by priority, then by arrival.
+type eventQueue []queuedEvent
+
+func (q eventQueue) Len() int { return len(q) }
+
+func (q eventQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q eventQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *eventQueue) Push(x interface{}) { *q = append(*q, x.(queuedEvent)) }
+
+func (q *eventQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	x := old[n-1]
+	*q = old[:n-1]
+	return x
+}
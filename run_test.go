@@ -0,0 +1,125 @@
+package fsm
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// channelProcessor records actions and can feed events into a channel while an action runs.
+type channelProcessor struct {
+	recordingProcessor
+	events      chan string
+	closeOnStop bool
+}
+
+func (p *channelProcessor) ActionContext(c *EventContext) error {
+	p.calls = append(p.calls, c.Action)
+	if c.Action == "low" {
+		p.events <- "High"
+		c.Emit("Follow")
+	}
+	if c.Action == "stop" && p.closeOnStop {
+		close(p.events)
+	}
+	return nil
+}
+
+func priorityFSM(p EventProcessor) *StateMachine {
+	return NewStateMachine(&DefaultDelegate{P: p},
+		Transition{From: "S", Event: "Low", To: "S", Action: "low"},
+		Transition{From: "S", Event: "High", To: "S", Action: "high"},
+		Transition{From: "S", Event: "Follow", To: "S", Action: "follow"},
+		Transition{From: "S", Event: "Stop", To: "Stopped", Action: "stop"},
+	)
+}
+
+func byName(event string) int {
+	if event == "High" {
+		return 10
+	}
+	return 0
+}
+
+func TestRun(t *testing.T) {
+	events := make(chan string, 10)
+	for _, e := range []string{"Push", "Coin", "Push"} {
+		events <- e
+	}
+	close(events)
+
+	ts := &Turnstile{ID: 1, State: "Locked"}
+	state, err := initFSM().Run(context.Background(), ts.State, events, ts)
+	if err != nil {
+		t.Fatalf("run err: %v", err)
+	}
+	if state != "Locked" || ts.PassCount != 1 || ts.EventCount != 3 {
+		t.Errorf("unexpected result %s, %+v", state, ts)
+	}
+}
+
+func TestRunPriority(t *testing.T) {
+	p := &channelProcessor{events: make(chan string, 10)}
+	p.events <- "Stop"
+	p.events <- "Follow"
+	p.events <- "High"
+	close(p.events)
+
+	byStop := func(event string) int {
+		if event == "Stop" {
+			return -1
+		}
+		return byName(event)
+	}
+	state, err := priorityFSM(p).RunPriority(context.Background(), "S", p.events, byStop)
+	if err != nil {
+		t.Fatalf("run err: %v", err)
+	}
+	if state != "Stopped" || fmt.Sprint(p.calls) != "[high follow exit:S stop enter:Stopped]" {
+		t.Errorf("unexpected result %s, %v", state, p.calls)
+	}
+}
+
+func TestRunPriorityRunToCompletion(t *testing.T) {
+	p := &channelProcessor{events: make(chan string, 10), closeOnStop: true}
+	p.events <- "Low"
+	p.events <- "Stop"
+
+	// the stop action closes the channel, so RunPriority returns once the pending events are processed
+	if _, err := priorityFSM(p).RunPriority(context.Background(), "S", p.events, byName); err != nil {
+		t.Fatalf("run err: %v", err)
+	}
+
+	// High is sent by the action of Low, it runs after the follow-up event of Low
+	if fmt.Sprint(p.calls) != "[low follow high exit:S stop enter:Stopped]" {
+		t.Errorf("unexpected order: %v", p.calls)
+	}
+}
+
+func TestRunCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	state, err := initFSM().Run(ctx, "Locked", make(chan string))
+	if err != context.Canceled || state != "Locked" {
+		t.Errorf("expected context.Canceled in Locked, got %s, %v", state, err)
+	}
+}
+
+func TestRunPriorityBoundedQueue(t *testing.T) {
+	p := &recordingProcessor{}
+	events := make(chan string, maxPendingEvents+1)
+	for i := 0; i < maxPendingEvents; i++ {
+		events <- "Low"
+	}
+	events <- "High"
+	close(events)
+
+	if _, err := priorityFSM(p).RunPriority(context.Background(), "S", events, byName); err != nil {
+		t.Fatalf("run err: %v", err)
+	}
+	// High is beyond the queued events, so it can't take precedence over the first one
+	if len(p.calls) != maxPendingEvents+1 || p.calls[0] != "action:low" || p.calls[1] != "action:high" {
+		t.Errorf("unexpected order: %v", p.calls[:2])
+	}
+}
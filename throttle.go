@@ -0,0 +1,61 @@
+package fsm
+
+import (
+	"errors"
+	"time"
+)
+
+// TriggerThrottled fires a event at most once per minInterval for the key, e.g. the ID of the processing object.
+// It returns false without touching the delegate if the previous accepted event for the key was processed too recently.
+// An event without a matching transition is not accepted, so it doesn't throttle the following events. An event
+// accepted for the key while it was matched is kept.
+// Time is read from the clock of the state machine, see WithClock. Expired keys are evicted.
+func (m *StateMachine) TriggerThrottled(key string, minInterval time.Duration, currentState string, event string, args ...interface{}) (bool, error) {
+	now := m.clock.Now()
+
+	m.throttleMu.Lock()
+	prev, ok := m.throttled[key]
+	if ok && prev.After(now) {
+		m.throttleMu.Unlock()
+		return false, nil
+	}
+	if m.throttled == nil {
+		m.throttled = make(map[string]time.Time)
+	}
+	until := now.Add(minInterval)
+	m.throttled[key] = until
+	m.evictThrottled(now)
+	m.throttleMu.Unlock()
+
+	err := m.Trigger(currentState, event, args...)
+	if errors.Is(err, ErrNoTransition) {
+		m.throttleMu.Lock()
+		// roll back unless another event for the key was accepted in the meantime
+		if current, set := m.throttled[key]; set && current.Equal(until) {
+			if ok {
+				m.throttled[key] = prev
+			} else {
+				delete(m.throttled, key)
+			}
+		}
+		m.throttleMu.Unlock()
+	}
+	return true, err
+}
+
+// evictThrottled removes the expired keys. It only scans when the map has doubled since the last scan,
+// so the cost is amortized over the triggers. It must be called with throttleMu held.
+func (m *StateMachine) evictThrottled(now time.Time) {
+	if len(m.throttled) < m.throttleScanAt {
+		return
+	}
+	for key, until := range m.throttled {
+		if !until.After(now) {
+			delete(m.throttled, key)
+		}
+	}
+	m.throttleScanAt = 2 * len(m.throttled)
+	if m.throttleScanAt < 64 {
+		m.throttleScanAt = 64
+	}
+}
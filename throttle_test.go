@@ -0,0 +1,102 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTriggerThrottled(t *testing.T) {
+	clock := newFakeClock()
+	p := &recordingProcessor{}
+	fsm := NewStateMachine(&DefaultDelegate{P: p},
+		Transition{From: "Locked", Event: "Push", To: "Locked", Action: "invalid-push"},
+	).With(WithClock(clock))
+
+	trigger := func(key string) bool {
+		processed, err := fsm.TriggerThrottled(key, time.Second, "Locked", "Push")
+		if err != nil {
+			t.Fatalf("trigger err: %v", err)
+		}
+		return processed
+	}
+
+	if !trigger("t1") {
+		t.Error("expected the first event to be processed")
+	}
+	clock.Advance(500 * time.Millisecond)
+	if trigger("t1") {
+		t.Error("expected the event within the interval to be dropped")
+	}
+	if !trigger("t2") {
+		t.Error("expected the event for another key to be processed")
+	}
+	clock.Advance(500 * time.Millisecond)
+	if !trigger("t1") {
+		t.Error("expected the event after the interval to be processed")
+	}
+
+	if len(p.calls) != 3 {
+		t.Errorf("expected 3 actions, got %v", p.calls)
+	}
+}
+
+func TestTriggerThrottledUnmatched(t *testing.T) {
+	clock := newFakeClock()
+	fsm := NewStateMachine(&DefaultDelegate{P: &recordingProcessor{}},
+		Transition{From: "Locked", Event: "Push", To: "Locked", Action: "invalid-push"},
+	).With(WithClock(clock))
+
+	if processed, err := fsm.TriggerThrottled("t1", time.Second, "Locked", "Kick"); !processed || err == nil {
+		t.Fatalf("expected the unmatched event to fail, got %v, %v", processed, err)
+	}
+	if processed, err := fsm.TriggerThrottled("t1", time.Second, "Locked", "Push"); !processed || err != nil {
+		t.Errorf("unmatched events should not throttle, got %v, %v", processed, err)
+	}
+}
+
+func TestTriggerThrottledConcurrentRollback(t *testing.T) {
+	clock := newFakeClock()
+	matching, release := make(chan struct{}), make(chan struct{})
+	fsm := NewStateMachine(nil,
+		Transition{From: "Locked", Event: "Kick", To: "Broken", Guard: func(from, event string, args []interface{}) bool {
+			close(matching)
+			<-release
+			return false
+		}},
+		Transition{From: "Locked", Event: "Push", To: "Locked"},
+	).With(WithClock(clock))
+
+	done := make(chan error)
+	go func() {
+		_, err := fsm.TriggerThrottled("t1", time.Second, "Locked", "Kick")
+		done <- err
+	}()
+	<-matching
+	clock.Advance(time.Second)
+	if processed, err := fsm.TriggerThrottled("t1", time.Second, "Locked", "Push"); !processed || err != nil {
+		t.Fatalf("expected the event after the interval to be processed, got %v, %v", processed, err)
+	}
+	close(release)
+	if err := <-done; !errors.Is(err, ErrNoTransition) {
+		t.Fatalf("expected the kick to fail, got %v", err)
+	}
+
+	if processed, _ := fsm.TriggerThrottled("t1", time.Second, "Locked", "Push"); processed {
+		t.Error("expected the accepted push to keep throttling after the rollback of the kick")
+	}
+}
+
+func TestTriggerThrottledEviction(t *testing.T) {
+	clock := newFakeClock()
+	fsm := NewStateMachine(nil, Transition{From: "Locked", Event: "Push", To: "Locked"}).With(WithClock(clock))
+
+	for i := 0; i < 1000; i++ {
+		fsm.TriggerThrottled(fmt.Sprint(i), time.Second, "Locked", "Push")
+		clock.Advance(100 * time.Millisecond)
+	}
+	if n := len(fsm.throttled); n > 200 {
+		t.Errorf("expected expired keys to be evicted, got %d keys", n)
+	}
+}
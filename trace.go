@@ -0,0 +1,49 @@
+package fsm
+
+import "fmt"
+
+// TraceStep is a recorded transition of a processing object, e.g. parsed from production logs.
+type TraceStep struct {
+	From  string
+	Event string
+	To    string
+}
+
+// TraceError is the first inconsistency ValidateTrace finds.
+type TraceError struct {
+	// Index is the index of the inconsistent step.
+	Index  int
+	Step   TraceStep
+	Reason string
+}
+
+func (e *TraceError) Error() string {
+	return fmt.Sprintf("state machine error: step %d (%s --%s--> %s) %s", e.Index, e.Step.From, e.Step.Event, e.Step.To, e.Reason)
+}
+
+// ValidateTrace checks that the steps are consistent with the transitions: each step starts in the state the previous
+// step ended in, and a transition for its From and Event leads to its To. Guards are not evaluated since the args
+// are not recorded, so any matching transition is accepted. Redirects by actions can't be validated.
+// It returns a *TraceError for the first inconsistent step, or nil.
+func (m *StateMachine) ValidateTrace(steps []TraceStep) error {
+	for i, step := range steps {
+		if i > 0 && step.From != steps[i-1].To {
+			return &TraceError{Index: i, Step: step, Reason: fmt.Sprintf("doesn't start in %s", steps[i-1].To)}
+		}
+
+		matched, valid := false, false
+		for _, t := range m.transitions {
+			if matchRank(t, step.From, m.normalizeEvent(step.Event)) > 0 {
+				matched = true
+				valid = valid || t.To == step.To
+			}
+		}
+		if !matched {
+			return &TraceError{Index: i, Step: step, Reason: "has no transition"}
+		}
+		if !valid {
+			return &TraceError{Index: i, Step: step, Reason: "has no transition to " + step.To}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package fsm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateTrace(t *testing.T) {
+	fsm := initFSM()
+
+	valid := []TraceStep{
+		{"Locked", "Push", "Locked"},
+		{"Locked", "Coin", "Unlocked"},
+		{"Unlocked", "Push", "Locked"},
+	}
+	if err := fsm.ValidateTrace(valid); err != nil {
+		t.Errorf("expected a valid trace, got %v", err)
+	}
+
+	tests := []struct {
+		steps []TraceStep
+		index int
+	}{
+		{[]TraceStep{{"Locked", "Coin", "Unlocked"}, {"Locked", "Coin", "Unlocked"}}, 1},
+		{[]TraceStep{{"Locked", "Kick", "Unlocked"}}, 0},
+		{[]TraceStep{{"Locked", "Push", "Locked"}, {"Locked", "Coin", "Locked"}}, 1},
+	}
+	for _, tt := range tests {
+		var te *TraceError
+		if err := fsm.ValidateTrace(tt.steps); !errors.As(err, &te) || te.Index != tt.index {
+			t.Errorf("expected an error at step %d for %v, got %v", tt.index, tt.steps, err)
+		}
+	}
+}
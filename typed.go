@@ -0,0 +1,167 @@
+package fsm
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// TypedTransition is a Transition whose states and events have their own string types,
+// so the compiler rejects a state used as an event and vice versa.
+type TypedTransition[S ~string, E ~string] struct {
+	From   S
+	Event  E
+	To     S
+	Action string
+
+	// Froms, Guard, ContextGuard, GuardName, Condition, OnGuardFail, OnFailure, Timeout, Tags, Weight and Deprecated have the same meaning as in Transition.
+	Froms        []S
+	OnGuardFail  S
+	OnFailure    S
+	Timeout      time.Duration
+	Tags         []string
+	Weight       int
+	Guard        Guard
+	ContextGuard ContextGuard
+	GuardName    string
+	Condition    string
+	Deprecated   bool
+}
+
+// Transition converts it into a plain Transition.
+func (t TypedTransition[S, E]) Transition() Transition {
+	var froms []string
+	for _, from := range t.Froms {
+		froms = append(froms, string(from))
+	}
+	return Transition{From: string(t.From), Event: string(t.Event), To: string(t.To), Action: t.Action, Froms: froms,
+		Guard: t.Guard, ContextGuard: t.ContextGuard, GuardName: t.GuardName, Condition: t.Condition, OnGuardFail: string(t.OnGuardFail), OnFailure: string(t.OnFailure), Timeout: t.Timeout, Tags: t.Tags, Weight: t.Weight, Deprecated: t.Deprecated}
+}
+
+// TypedTransitionOf converts a plain Transition into a TypedTransition.
+func TypedTransitionOf[S ~string, E ~string](t Transition) TypedTransition[S, E] {
+	var froms []S
+	for _, from := range t.Froms {
+		froms = append(froms, S(from))
+	}
+	return TypedTransition[S, E]{From: S(t.From), Event: E(t.Event), To: S(t.To), Action: t.Action, Froms: froms,
+		Guard: t.Guard, ContextGuard: t.ContextGuard, GuardName: t.GuardName, Condition: t.Condition, OnGuardFail: S(t.OnGuardFail), OnFailure: S(t.OnFailure), Timeout: t.Timeout, Tags: t.Tags, Weight: t.Weight, Deprecated: t.Deprecated}
+}
+
+// TypedTriggerItem is a TriggerItem with typed state and event.
+type TypedTriggerItem[S ~string, E ~string] struct {
+	CurrentState S
+	Event        E
+	Args         []interface{}
+}
+
+// TypedStateMachine is a StateMachine with typed states and events. Define the types as
+//
+//	type State string
+//	type Event string
+//
+// and their values as constants. It wraps a plain StateMachine, so StateMachine keeps working with plain strings.
+// Methods taking states or events are typed, use Untyped for the other methods of the wrapped machine.
+type TypedStateMachine[S ~string, E ~string] struct {
+	m *StateMachine
+}
+
+// NewTypedStateMachine creates a new typed state machine.
+func NewTypedStateMachine[S ~string, E ~string](delegate Delegate, transitions ...TypedTransition[S, E]) *TypedStateMachine[S, E] {
+	trans := make([]Transition, 0, len(transitions))
+	for _, t := range transitions {
+		trans = append(trans, t.Transition())
+	}
+	return &TypedStateMachine[S, E]{m: NewStateMachine(delegate, trans...)}
+}
+
+// Untyped returns the wrapped plain state machine.
+func (m *TypedStateMachine[S, E]) Untyped() *StateMachine {
+	return m.m
+}
+
+// With applies options to the state machine and returns it, see StateMachine.With.
+func (m *TypedStateMachine[S, E]) With(options ...OptionFn) *TypedStateMachine[S, E] {
+	m.m.With(options...)
+	return m
+}
+
+// Trigger fires a typed event, see StateMachine.Trigger.
+func (m *TypedStateMachine[S, E]) Trigger(currentState S, event E, args ...interface{}) error {
+	return m.m.Trigger(string(currentState), string(event), args...)
+}
+
+// TriggerWithValues fires a typed event with named values, see StateMachine.TriggerWithValues.
+func (m *TypedStateMachine[S, E]) TriggerWithValues(currentState S, event E, values Values) error {
+	return m.m.TriggerWithValues(string(currentState), string(event), values)
+}
+
+// TriggerThrottled fires a typed event at most once per minInterval for the key, see StateMachine.TriggerThrottled.
+func (m *TypedStateMachine[S, E]) TriggerThrottled(key string, minInterval time.Duration, currentState S, event E, args ...interface{}) (bool, error) {
+	return m.m.TriggerThrottled(key, minInterval, string(currentState), string(event), args...)
+}
+
+// TriggerBatch fires typed events concurrently, see StateMachine.TriggerBatch.
+func (m *TypedStateMachine[S, E]) TriggerBatch(ctx context.Context, items []TypedTriggerItem[S, E], concurrency int) []error {
+	plain := make([]TriggerItem, 0, len(items))
+	for _, item := range items {
+		plain = append(plain, TriggerItem{CurrentState: string(item.CurrentState), Event: string(item.Event), Args: item.Args})
+	}
+	return m.m.TriggerBatch(ctx, plain, concurrency)
+}
+
+// FindTransition returns the transition that would be processed, see StateMachine.FindTransition.
+func (m *TypedStateMachine[S, E]) FindTransition(currentState S, event E, args ...interface{}) (*TypedTransition[S, E], bool) {
+	trans, ok := m.m.FindTransition(string(currentState), string(event), args...)
+	if !ok {
+		return nil, false
+	}
+	t := TypedTransitionOf[S, E](*trans)
+	return &t, true
+}
+
+// Reset informs the delegate that the whole system is reset, see StateMachine.Reset.
+func (m *TypedStateMachine[S, E]) Reset(args ...interface{}) {
+	m.m.Reset(args...)
+}
+
+// AvailableEvents returns the distinct events that have a transition from the state, see StateMachine.AvailableEvents.
+func (m *TypedStateMachine[S, E]) AvailableEvents(state S) []E {
+	var events []E
+	for _, e := range m.m.AvailableEvents(string(state)) {
+		events = append(events, E(e))
+	}
+	return events
+}
+
+// Guards returns the distinct guard names, see StateMachine.Guards.
+func (m *TypedStateMachine[S, E]) Guards() []string {
+	return m.m.Guards()
+}
+
+// Export exports the state diagram into a file, see StateMachine.Export.
+func (m *TypedStateMachine[S, E]) Export(outfile string) error {
+	return m.m.Export(outfile)
+}
+
+// WriteDot writes the state diagram in graphviz DOT language, see StateMachine.WriteDot.
+func (m *TypedStateMachine[S, E]) WriteDot(w io.Writer) error {
+	return m.m.WriteDot(w)
+}
+
+// ExportNeighborhood writes the diagram around a typed state, see StateMachine.ExportNeighborhood.
+func (m *TypedStateMachine[S, E]) ExportNeighborhood(w io.Writer, state S, depth int) error {
+	return m.m.ExportNeighborhood(w, string(state), depth)
+}
+
+// ExportFiltered writes the DOT diagram of the kept transitions, see StateMachine.ExportFiltered.
+func (m *TypedStateMachine[S, E]) ExportFiltered(w io.Writer, keep func(TypedTransition[S, E]) bool) error {
+	return m.m.ExportFiltered(w, func(t Transition) bool {
+		return keep(TypedTransitionOf[S, E](t))
+	})
+}
+
+// ExportHighlight writes the diagram with a typed state highlighted, see StateMachine.ExportHighlight.
+func (m *TypedStateMachine[S, E]) ExportHighlight(w io.Writer, currentState S) error {
+	return m.m.ExportHighlight(w, string(currentState))
+}
@@ -0,0 +1,68 @@
+package fsm
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+type turnstileState string
+
+type turnstileEvent string
+
+const (
+	stateLocked   turnstileState = "Locked"
+	stateUnlocked turnstileState = "Unlocked"
+
+	eventCoin turnstileEvent = "Coin"
+	eventPush turnstileEvent = "Push"
+)
+
+func TestTypedStateMachine(t *testing.T) {
+	delegate := &DefaultDelegate{P: &TurnstileEventProcessor{}}
+	fsm := NewTypedStateMachine(delegate,
+		TypedTransition[turnstileState, turnstileEvent]{From: stateLocked, Event: eventCoin, To: stateUnlocked, Action: "check"},
+		TypedTransition[turnstileState, turnstileEvent]{From: stateLocked, Event: eventPush, To: stateLocked, Action: "invalid-push"},
+		TypedTransition[turnstileState, turnstileEvent]{From: stateUnlocked, Event: eventPush, To: stateLocked, Action: "pass"},
+	)
+
+	ts := &Turnstile{ID: 1, State: string(stateLocked)}
+	if err := fsm.Trigger(turnstileState(ts.State), eventCoin, ts); err != nil {
+		t.Fatalf("trigger err: %v", err)
+	}
+	if err := fsm.Trigger(turnstileState(ts.State), eventPush, ts); err != nil {
+		t.Fatalf("trigger err: %v", err)
+	}
+	if ts.State != string(stateLocked) || ts.CoinCount != 1 || ts.PassCount != 1 {
+		t.Errorf("unexpected turnstile: %+v", ts)
+	}
+
+	if err := fsm.Trigger(stateUnlocked, eventCoin, ts); err == nil {
+		t.Error("expected an error for an undefined transition")
+	}
+}
+
+func TestTypedStateMachineWrappers(t *testing.T) {
+	fsm := NewTypedStateMachine(&DefaultDelegate{P: &counterProcessor{}},
+		TypedTransition[turnstileState, turnstileEvent]{From: stateLocked, Event: eventCoin, To: stateUnlocked, Action: "check"},
+	)
+
+	trans, ok := fsm.FindTransition(stateLocked, eventCoin)
+	if !ok || trans.To != stateUnlocked {
+		t.Errorf("unexpected transition: %v", trans)
+	}
+
+	ts := &Turnstile{ID: 1, State: string(stateLocked)}
+	errs := fsm.TriggerBatch(context.Background(), []TypedTriggerItem[turnstileState, turnstileEvent]{
+		{CurrentState: stateLocked, Event: eventCoin, Args: []interface{}{ts}},
+		{CurrentState: stateUnlocked, Event: eventCoin, Args: []interface{}{ts}},
+	}, 1)
+	if errs[0] != nil || errs[1] == nil || ts.State != string(stateUnlocked) {
+		t.Errorf("unexpected batch result %v, %+v", errs, ts)
+	}
+
+	var dot strings.Builder
+	if err := fsm.ExportHighlight(&dot, stateUnlocked); err != nil || !strings.Contains(dot.String(), `"Unlocked" [color=red`) {
+		t.Errorf("unexpected diagram %v: %s", err, dot.String())
+	}
+}
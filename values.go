@@ -0,0 +1,19 @@
+package fsm
+
+// Values are named arguments passed to delegates by TriggerWithValues.
+type Values map[string]interface{}
+
+// TriggerWithValues fires a event with named values instead of positional args.
+// The values are passed to delegate callbacks as the only element of args, use ValuesOf to get them back.
+func (m *StateMachine) TriggerWithValues(currentState string, event string, values Values) error {
+	return m.Trigger(currentState, event, values)
+}
+
+// ValuesOf returns the Values passed by TriggerWithValues, or nil if args don't carry Values.
+func ValuesOf(args []interface{}) Values {
+	if len(args) == 0 {
+		return nil
+	}
+	v, _ := args[0].(Values)
+	return v
+}
@@ -0,0 +1,39 @@
+package fsm
+
+import "testing"
+
+// valuesProcessor handles turnstile actions with named values.
+type valuesProcessor struct{}
+
+func (p *valuesProcessor) OnExit(fromState string, args []interface{}) {}
+
+func (p *valuesProcessor) OnActionFailure(action string, fromState string, toState string, args []interface{}, err error) {
+}
+
+func (p *valuesProcessor) Action(action string, fromState string, toState string, args []interface{}) error {
+	ts := ValuesOf(args)["turnstile"].(*Turnstile)
+	ts.EventCount += uint64(ValuesOf(args)["count"].(int))
+	return nil
+}
+
+func (p *valuesProcessor) OnEnter(toState string, args []interface{}) {
+	ValuesOf(args)["turnstile"].(*Turnstile).State = toState
+}
+
+func TestTriggerWithValues(t *testing.T) {
+	fsm := NewStateMachine(&DefaultDelegate{P: &valuesProcessor{}},
+		Transition{From: "Locked", Event: "Coin", To: "Unlocked", Action: "check"})
+
+	ts := &Turnstile{ID: 1, State: "Locked"}
+	err := fsm.TriggerWithValues(ts.State, "Coin", Values{"turnstile": ts, "count": 2})
+	if err != nil {
+		t.Fatalf("trigger err: %v", err)
+	}
+	if ts.State != "Unlocked" || ts.EventCount != 2 {
+		t.Errorf("unexpected turnstile: %+v", ts)
+	}
+
+	if ValuesOf(nil) != nil || ValuesOf([]interface{}{ts}) != nil {
+		t.Error("expected nil values for positional args")
+	}
+}
@@ -0,0 +1,32 @@
+package fsm
+
+import "context"
+
+// Watchdog schedules the timeout event of the state: when an object stays in the state for the Timeout of
+// a transition from it, emit is called with the event of that transition, e.g. to trigger it. If the state has
+// several timeout transitions, the shortest one is scheduled. It returns immediately, the timer is measured with
+// the clock of the state machine and stopped by cancelling ctx, e.g. when the object leaves the state.
+// Nothing is scheduled if the state has no timeout transition.
+func (m *StateMachine) Watchdog(ctx context.Context, state string, emit func(event string)) {
+	var timeout *Transition
+	for i, t := range m.transitions {
+		if t.From == state && t.Timeout > 0 && (timeout == nil || t.Timeout < timeout.Timeout) {
+			timeout = &m.transitions[i]
+		}
+	}
+	if timeout == nil {
+		return
+	}
+
+	event := timeout.Event
+	after := m.clock.After(timeout.Timeout)
+	go func() {
+		select {
+		case <-after:
+			if ctx.Err() == nil {
+				emit(event)
+			}
+		case <-ctx.Done():
+		}
+	}()
+}
@@ -0,0 +1,46 @@
+package fsm
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWatchdog(t *testing.T) {
+	clock := newFakeClock()
+	fsm := NewStateMachine(nil,
+		Transition{From: "Pending", Event: "Pay", To: "Paid"},
+		Transition{From: "Pending", Event: "Remind", To: "Pending", Timeout: time.Minute},
+		Transition{From: "Pending", Event: "Expire", To: "Expired", Timeout: time.Hour},
+	).With(WithClock(clock))
+
+	events := make(chan string, 1)
+	emit := func(event string) { events <- event }
+
+	fsm.Watchdog(context.Background(), "Pending", emit)
+	clock.Advance(59 * time.Second)
+	select {
+	case e := <-events:
+		t.Fatalf("timeout %s fired too early", e)
+	case <-time.After(10 * time.Millisecond):
+	}
+	clock.Advance(time.Second)
+	select {
+	case e := <-events:
+		if e != "Remind" {
+			t.Errorf("expected the shortest timeout Remind, got %s", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the timeout event")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	fsm.Watchdog(ctx, "Pending", emit)
+	cancel()
+	clock.Advance(time.Minute)
+	select {
+	case e := <-events:
+		t.Errorf("expected no event after cancel, got %s", e)
+	case <-time.After(10 * time.Millisecond):
+	}
+}